@@ -0,0 +1,8 @@
+-- secret of TOTP is saved on enrollment and used on login after confirmation,
+-- recovery codes are SHA-256 hashes removed once used,
+-- codes of time steps up to totp_last_counter are already used and can't be replayed
+ALTER TABLE users
+    ADD COLUMN totp_secret varchar(64) NOT NULL DEFAULT '',
+    ADD COLUMN totp_enabled boolean NOT NULL DEFAULT false,
+    ADD COLUMN totp_last_counter bigint NOT NULL DEFAULT 0,
+    ADD COLUMN recovery_codes text[] NOT NULL DEFAULT '{}';
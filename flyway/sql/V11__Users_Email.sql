@@ -0,0 +1,6 @@
+-- users registered before have no email, they can't reset password
+ALTER TABLE users
+    ADD COLUMN email varchar(254),
+    ADD COLUMN email_verified boolean NOT NULL DEFAULT false;
+
+CREATE UNIQUE INDEX users_email_key ON users (lower(email));
@@ -0,0 +1,14 @@
+-- users without username can't log in, they get one to make the column required
+UPDATE users SET username = 'user-' || left(id::text, 8) WHERE username IS NULL;
+
+-- duplicates created before the index are renamed, the account with the least id keeps username
+UPDATE users u
+SET username = u.username || '-' || left(u.id::text, 8)
+WHERE EXISTS (
+    SELECT 1 FROM users other
+    WHERE lower(other.username) = lower(u.username) AND other.id < u.id
+);
+
+ALTER TABLE users ALTER COLUMN username SET NOT NULL;
+
+CREATE UNIQUE INDEX users_username_key ON users (lower(username));
@@ -0,0 +1,5 @@
+ALTER TABLE cats ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- keyset pagination indexes, names are ordered bytewise to match mongodb
+CREATE INDEX cats_name_id_idx ON cats (name COLLATE "C", id);
+CREATE INDEX cats_created_at_id_idx ON cats (created_at, id);
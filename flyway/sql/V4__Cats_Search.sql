@@ -0,0 +1,6 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- full-text search matches whole words of names without stemming
+CREATE INDEX cats_name_tsv_idx ON cats USING GIN (to_tsvector('simple', name));
+-- fuzzy search tolerates typos, e.g. 'Barsik' and 'Barsyk'
+CREATE INDEX cats_name_trgm_idx ON cats USING GIN (name gin_trgm_ops);
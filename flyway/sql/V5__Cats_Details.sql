@@ -0,0 +1,14 @@
+ALTER TABLE cats
+    ALTER COLUMN id SET NOT NULL,
+    ALTER COLUMN name SET NOT NULL,
+    ADD PRIMARY KEY (id),
+    ADD COLUMN breed varchar(120) NOT NULL DEFAULT '',
+    ADD COLUMN birth_date DATE,
+    ADD COLUMN sex varchar(16) NOT NULL DEFAULT ''
+        CHECK (sex IN ('', 'male', 'female')),
+    ADD COLUMN color varchar(60) NOT NULL DEFAULT '',
+    ADD COLUMN weight NUMERIC(5, 2) NOT NULL DEFAULT 0
+        CHECK (weight >= 0),
+    ADD COLUMN status varchar(16) NOT NULL DEFAULT 'available'
+        CHECK (status IN ('available', 'adopted', 'deceased')),
+    ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
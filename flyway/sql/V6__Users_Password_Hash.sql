@@ -0,0 +1,2 @@
+-- argon2id hashes with encoded parameters are longer than legacy SHA-256 ones
+ALTER TABLE users ALTER COLUMN password TYPE varchar(255);
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN role varchar(20) NOT NULL DEFAULT 'viewer'
+        CHECK (role IN ('admin', 'staff', 'viewer'));
@@ -0,0 +1,5 @@
+-- cats created before ownership have no owner and can be changed by admins only
+ALTER TABLE cats
+    ADD COLUMN owner_id UUID REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX cats_owner_id_created_at_idx ON cats (owner_id, created_at, id);
@@ -0,0 +1,86 @@
+// Package apperror provides kinds of errors shared by layers of app, handlers map kinds to HTTP statuses
+package apperror
+
+import "errors"
+
+// Kind is a category of error telling how client may react to it
+type Kind int
+
+// Kinds of errors, errors of unknown kind are internal ones
+const (
+	Internal Kind = iota
+	NotFound
+	Conflict
+	Validation
+	Unauthorized
+	Forbidden
+	TooManyRequests
+)
+
+var kindNames = map[Kind]string{
+	Internal:        "internal error",
+	NotFound:        "not found",
+	Conflict:        "conflict",
+	Validation:      "validation failed",
+	Unauthorized:    "unauthorized",
+	Forbidden:       "forbidden",
+	TooManyRequests: "too many requests",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// Sentinels matching every error of their kind by errors.Is
+var (
+	ErrNotFound        = &Error{Kind: NotFound}
+	ErrConflict        = &Error{Kind: Conflict}
+	ErrValidation      = &Error{Kind: Validation}
+	ErrUnauthorized    = &Error{Kind: Unauthorized}
+	ErrForbidden       = &Error{Kind: Forbidden}
+	ErrTooManyRequests = &Error{Kind: TooManyRequests}
+)
+
+// Error is an error of 'Kind', its message is safe to show to clients
+type Error struct {
+	Kind Kind
+	Msg  string
+	// Err is a wrapped error, it lets the same sentinel be of other kind in other context
+	Err error
+}
+
+// New returns sentinel error of 'kind' with 'msg'
+func New(kind Kind, msg string) *Error {
+	return &Error{Kind: kind, Msg: msg}
+}
+
+// Wrap returns 'err' as an error of 'kind', errors.Is still matches 'err'
+func Wrap(kind Kind, err error) *Error {
+	return &Error{Kind: kind, Msg: err.Error(), Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Msg == "" {
+		return e.Kind.String()
+	}
+	return e.Msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is makes every error match sentinel of its kind like ErrNotFound
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Msg == "" && t.Err == nil && t.Kind == e.Kind
+}
+
+// KindOf returns kind of the outermost Error in chain of 'err', it's Internal if there is none
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return Internal
+}
@@ -0,0 +1,60 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errCatNotFound = New(NotFound, "cat doesn't exist in database")
+
+func TestError(t *testing.T) {
+	TestTable := []struct {
+		name       string
+		inputErr   error
+		exceptKind Kind
+		exceptIs   []error
+		exceptNot  []error
+	}{
+		{
+			name:       "sentinel",
+			inputErr:   errCatNotFound,
+			exceptKind: NotFound,
+			exceptIs:   []error{errCatNotFound, ErrNotFound},
+			exceptNot:  []error{ErrConflict},
+		},
+		{
+			name:       "wrapped by fmt",
+			inputErr:   fmt.Errorf("get cat: %w", errCatNotFound),
+			exceptKind: NotFound,
+			exceptIs:   []error{errCatNotFound, ErrNotFound},
+		},
+		{
+			name:       "wrapped as other kind",
+			inputErr:   Wrap(Validation, errCatNotFound),
+			exceptKind: Validation,
+			exceptIs:   []error{errCatNotFound, ErrValidation},
+		},
+		{
+			name:       "plain error",
+			inputErr:   errors.New("connection refused"),
+			exceptKind: Internal,
+			exceptNot:  []error{ErrNotFound},
+		},
+	}
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			assert.Equal(t, TestCase.exceptKind, KindOf(TestCase.inputErr))
+			for _, target := range TestCase.exceptIs {
+				assert.ErrorIs(t, TestCase.inputErr, target)
+			}
+			for _, target := range TestCase.exceptNot {
+				assert.NotErrorIs(t, TestCase.inputErr, target)
+			}
+		})
+	}
+	assert.NotErrorIs(t, New(NotFound, "user doesn't exist in database"), errCatNotFound)
+}
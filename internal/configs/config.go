@@ -1,13 +1,20 @@
 // Package configs init env variables by caarlos0
 package configs
 
+import "time"
+
 // Config contains all env variables
 type Config struct {
+	// Backend selects a storage backend registered in repository (postgres / mongodb / memory)
+	Backend string `env:"DB_BACKEND" envDefault:"postgres"`
+
 	PgUser     string `env:"POSTGRES_USER" envDefault:"postgres"`
 	PgPassword string `env:"POSTGRES_PASSWORD" envDefault:"root"`
 	PgHost     string `env:"POSTGRES_HOST" envDefault:"localhost"`
 	PgPort     string `env:"POSTGRES_PORT" envDefault:"5432"`
 	PgDBName   string `env:"POSTGRES_DATABASE" envDefault:"postgres"`
+	// PgTimeout limits every single query to postgres
+	PgTimeout time.Duration `env:"POSTGRES_TIMEOUT" envDefault:"5s"`
 
 	MongoUser       string `env:"MONGO_USERNAME" envDefault:"userm"`
 	MongoPassword   string `env:"MONGO_PASSWORD" envDefault:"testpassw"`
@@ -15,10 +22,79 @@ type Config struct {
 	MongoPort       string `env:"MONGO_PORT" envDefault:"27017"`
 	MongoDBName     string `env:"MONGO_DBNAME" envDefault:"mongodb"`
 	MongoCollection string `env:"MONGO_COLLECTION" envDefault:"mongocl"`
+	// MongoUsersCollection and MongoAPIKeysCollection keep users and their API keys in MongoDBName
+	MongoUsersCollection   string `env:"MONGO_USERS_COLLECTION" envDefault:"users"`
+	MongoAPIKeysCollection string `env:"MONGO_API_KEYS_COLLECTION" envDefault:"api_keys"`
+	// MongoTimeout limits every single operation on mongodb
+	MongoTimeout time.Duration `env:"MONGO_TIMEOUT" envDefault:"5s"`
 
 	RedisHost string `env:"REDIS_HOST" envDefault:"localhost"`
 	RedisPort string `env:"REDIS_PORT" envDefault:"6379"`
+	// RedisTimeout limits every single command to redis
+	RedisTimeout time.Duration `env:"REDIS_TIMEOUT" envDefault:"1s"`
+	// CacheEnabled turns on caching of cats in redis, it's off by default so that memory backend needs no redis
+	CacheEnabled bool `env:"CACHE_ENABLED" envDefault:"false"`
+	// CacheTTL is a lifetime of cats cached in redis
+	CacheTTL time.Duration `env:"CACHE_TTL" envDefault:"10m"`
+	// CacheNegativeTTL is a lifetime of cached misses of cats in database
+	CacheNegativeTTL time.Duration `env:"CACHE_NEGATIVE_TTL" envDefault:"30s"`
+	// CacheLockTTL limits how long other instances wait for a cat read from database by one of them
+	CacheLockTTL time.Duration `env:"CACHE_LOCK_TTL" envDefault:"500ms"`
+	// CacheLoadTimeout limits read of cat missing in cache shared by concurrent requests,
+	// it isn't canceled when requests waiting for it are
+	CacheLoadTimeout time.Duration `env:"CACHE_LOAD_TIMEOUT" envDefault:"10s"`
+	// CacheEarlyRefreshBeta turns on probabilistic early refresh of cached cats when it's positive, 1 is a good choice
+	CacheEarlyRefreshBeta float64 `env:"CACHE_EARLY_REFRESH_BETA" envDefault:"0"`
 
+	// KeyForSignatureJwt is a shared secret to sign tokens by HS256 when JwtPrivateKeyFile isn't set
 	KeyForSignatureJwt string `env:"KEY_FOR_SIGNATURE_JWT" envDefault:"mySecret"`
-	Salt               string `env:"SALT_FOR_GENERATE_PASSWORD" envDefault:"l337c0d3"`
+	// JwtPrivateKeyFile is a PEM file with RSA or Ed25519 key to sign tokens by RS256 or EdDSA
+	JwtPrivateKeyFile string `env:"JWT_PRIVATE_KEY_FILE"`
+	// JwtPublicKeyFiles are PEM files with previous public keys still accepted for verification of tokens
+	JwtPublicKeyFiles []string `env:"JWT_PUBLIC_KEY_FILES" envSeparator:","`
+	// LoginFreeAttempts is a number of failed logins of user allowed without delay, every next one
+	// blocks logins of user for LoginBackoffBase doubled on each failure up to LoginBackoffMax
+	LoginFreeAttempts int64         `env:"LOGIN_FREE_ATTEMPTS" envDefault:"3"`
+	LoginBackoffBase  time.Duration `env:"LOGIN_BACKOFF_BASE" envDefault:"1s"`
+	LoginBackoffMax   time.Duration `env:"LOGIN_BACKOFF_MAX" envDefault:"1m"`
+	// LoginLockoutAttempts is a number of failed logins of user locking it out for LoginLockoutDuration
+	LoginLockoutAttempts int64         `env:"LOGIN_LOCKOUT_ATTEMPTS" envDefault:"10"`
+	LoginLockoutDuration time.Duration `env:"LOGIN_LOCKOUT_DURATION" envDefault:"15m"`
+	// LoginIPFreeAttempts and LoginIPLockoutAttempts are limits of failed logins from one IP,
+	// they are higher since many users may share it
+	LoginIPFreeAttempts    int64 `env:"LOGIN_IP_FREE_ATTEMPTS" envDefault:"10"`
+	LoginIPLockoutAttempts int64 `env:"LOGIN_IP_LOCKOUT_ATTEMPTS" envDefault:"50"`
+	// LoginFailureWindow is a time after the last failed login when count of failures is reset
+	LoginFailureWindow time.Duration `env:"LOGIN_FAILURE_WINDOW" envDefault:"15m"`
+	// TrustProxy makes IP of client taken from X-Forwarded-For header, it's only safe behind proxy
+	TrustProxy bool `env:"TRUST_PROXY" envDefault:"false"`
+	// Mailer selects delivery of emails: smtp, file (MailDir) or log,
+	// log drops emails and is only meant for local development
+	Mailer   string `env:"MAILER" envDefault:"smtp"`
+	MailFrom string `env:"MAIL_FROM" envDefault:"CatsGo <noreply@catsgo.local>"`
+	MailDir  string `env:"MAIL_DIR" envDefault:"mail"`
+	// MailConcurrency limits emails sent in background at once, emails over the limit are dropped
+	MailConcurrency int `env:"MAIL_CONCURRENCY" envDefault:"10"`
+	// SMTPHost and SMTPPort point to MailHog by default
+	SMTPHost     string `env:"SMTP_HOST" envDefault:"localhost"`
+	SMTPPort     string `env:"SMTP_PORT" envDefault:"1025"`
+	SMTPUsername string `env:"SMTP_USERNAME"`
+	SMTPPassword string `env:"SMTP_PASSWORD"`
+	// AppBaseURL is put in links sent by email
+	AppBaseURL string `env:"APP_BASE_URL" envDefault:"http://localhost:8000"`
+	// PasswordResetTTL and EmailVerificationTTL are lifetimes of single-use tokens sent by email
+	PasswordResetTTL     time.Duration `env:"PASSWORD_RESET_TTL" envDefault:"1h"`
+	EmailVerificationTTL time.Duration `env:"EMAIL_VERIFICATION_TTL" envDefault:"48h"`
+	// TOTPIssuer is shown by authenticator apps next to username
+	TOTPIssuer string `env:"TOTP_ISSUER" envDefault:"CatsGo"`
+	// AdminUsername is a username of user who gets admin role on sign up
+	AdminUsername string `env:"ADMIN_USERNAME"`
+	// Salt is used only to verify legacy SHA-256 hashes of passwords
+	Salt string `env:"SALT_FOR_GENERATE_PASSWORD" envDefault:"l337c0d3"`
+	// Argon2Memory is an amount of memory in KiB used to hash a password
+	Argon2Memory uint32 `env:"ARGON2_MEMORY" envDefault:"65536"`
+	// Argon2Time is a number of passes over the memory used to hash a password
+	Argon2Time uint32 `env:"ARGON2_TIME" envDefault:"1"`
+	// Argon2Threads is a number of threads used to hash a password
+	Argon2Threads uint8 `env:"ARGON2_THREADS" envDefault:"4"`
 }
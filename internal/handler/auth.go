@@ -3,12 +3,12 @@ package handler
 
 import (
 	"CatsGo/internal/models"
+	"CatsGo/internal/request"
 	"CatsGo/internal/service"
 	"encoding/json"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
-	log "github.com/sirupsen/logrus"
 )
 
 // UserAuthHandler init
@@ -28,25 +28,26 @@ func NewUserAuthHandler(src service.Auth) *UserAuthHandler {
 // @Accept json
 // @Produce json
 // @Param user body models.User true "user"
-// @Success 200 {integer} integer
-// @Failure 400 {object} models.User
-// @Failure 500 {object} models.User
+// @Success 200 {object} models.User
+// @Failure 400 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /register [post]
 func (h *UserAuthHandler) SignUp(c echo.Context) error {
 	var user models.User
 
 	err := json.NewDecoder(c.Request().Body).Decode(&user)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, new(models.User))
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
 	if err = c.Validate(user); err != nil {
-		return c.JSON(http.StatusBadRequest, new(models.User))
+		return err
 	}
 
-	id, err := h.src.CreateUserServ(user)
+	id, err := h.src.CreateUserServ(c.Request().Context(), user)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return err
 	}
 
 	return c.JSON(http.StatusOK, id)
@@ -64,6 +65,13 @@ type TokenResponse struct {
 	RefreshToken string `json:"refreshToken" validate:"required,jwt"`
 }
 
+// MFAChallengeResponse is returned by login instead of tokens when user has TOTP enabled,
+// MFAToken is exchanged for tokens at /login/mfa
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfaRequired" example:"true"`
+	MFAToken    string `json:"mfaToken"`
+}
+
 // RefreshTokenRequest struct init
 type RefreshTokenRequest struct {
 	Token string `json:"Token" validate:"required,jwt"`
@@ -72,61 +80,484 @@ type RefreshTokenRequest struct {
 // SignIn provides logic for login user
 // @Summary SignIn
 // @Tags auth
-// @Description decode params and send them in service for generate token
+// @Description decode params and send them in service for generate token, users with two-factor
+// @Description authentication get MFAChallengeResponse instead of tokens
 // @Accept json
 // @Produce json
 // @Param input body SignInInput true "input"
 // @Success 200 {object} TokenResponse
-// @Failure 400 {object} models.User
-// @Failure 500 {object} models.User
+// @Success 202 {object} MFAChallengeResponse
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 429 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /login [post]
 func (h *UserAuthHandler) SignIn(c echo.Context) error {
 	var input SignInInput
 
 	err := json.NewDecoder(c.Request().Body).Decode(&input)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, new(models.User))
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
 	if err = c.Validate(input); err != nil {
-		return c.JSON(http.StatusBadRequest, new(models.User))
+		return err
 	}
 
-	token, refToken, err := h.src.GenerateToken(input.Username, input.Password)
+	token, refToken, mfaToken, err := h.src.GenerateToken(c.Request().Context(), input.Username, input.Password, c.RealIP())
 	if err != nil {
-		log.Error(err)
-		return c.JSON(http.StatusInternalServerError, err)
+		return err
+	}
+	if mfaToken != "" {
+		return c.JSON(http.StatusAccepted, MFAChallengeResponse{MFARequired: true, MFAToken: mfaToken})
 	}
 	a := TokenResponse{AccessToken: token, RefreshToken: refToken}
 	return c.JSON(http.StatusOK, a)
 }
 
+// SignInMFA provides logic for second step of login of user with two-factor authentication
+// @Summary SignInMFA
+// @Tags auth
+// @Description exchange challenge token from login and TOTP or recovery code for tokens
+// @Accept json
+// @Produce json
+// @Param input body request.MFALogin true "input"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 429 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /login/mfa [post]
+func (h *UserAuthHandler) SignInMFA(c echo.Context) error {
+	var input request.MFALogin
+	if err := json.NewDecoder(c.Request().Body).Decode(&input); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+
+	token, refToken, err := h.src.LoginMFA(c.Request().Context(), input.MFAToken, input.Code)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, TokenResponse{AccessToken: token, RefreshToken: refToken})
+}
+
 // UpdateTokens provides logic for update users tokens
 // @Summary UpdateTokens
 // @Tags auth
 // @Description update access and refresh token pair
 // @Accept json
 // @Produce json
-// @Param t_input body TokenRespone true "t_input"
+// @Param t_input body RefreshTokenRequest true "t_input"
 // @Success 200 {object} TokenResponse
-// @Failure 400 {object} models.User
-// @Failure 500 {object} models.User
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /token [post]
 func (h *UserAuthHandler) UpdateTokens(c echo.Context) error {
 	var tInput RefreshTokenRequest
 
 	err := json.NewDecoder(c.Request().Body).Decode(&tInput)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, new(models.User))
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 	if err = c.Validate(tInput); err != nil {
-		return c.JSON(http.StatusBadRequest, new(models.User))
+		return err
 	}
-	ntoken, nrefToken, err := h.src.RefreshTokens(tInput.Token)
+	ntoken, nrefToken, err := h.src.RefreshTokens(c.Request().Context(), tInput.Token)
 	if err != nil {
-		log.Error(err)
-		return c.JSON(http.StatusInternalServerError, err)
+		return err
 	}
 	b := TokenResponse{AccessToken: ntoken, RefreshToken: nrefToken}
 	return c.JSON(http.StatusOK, b)
 }
+
+// Logout provides logic for logout of user on one device
+// @Summary Logout
+// @Security ApiKeyAuth
+// @Tags auth
+// @Description revoke refresh token and access token of request
+// @Accept json
+// @Param t_input body RefreshTokenRequest true "t_input"
+// @Success 204
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /logout [post]
+func (h *UserAuthHandler) Logout(c echo.Context) error {
+	var tInput RefreshTokenRequest
+
+	err := json.NewDecoder(c.Request().Body).Decode(&tInput)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err = c.Validate(tInput); err != nil {
+		return err
+	}
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	err = h.src.Logout(c.Request().Context(), claims, tInput.Token)
+	if err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// LogoutAll provides logic for logout of user on all devices
+// @Summary LogoutAll
+// @Security ApiKeyAuth
+// @Tags auth
+// @Description revoke all tokens of user issued before
+// @Success 204
+// @Failure 401 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /logout/all [post]
+func (h *UserAuthHandler) LogoutAll(c echo.Context) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+	if err := h.src.LogoutAll(c.Request().Context(), claims); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// JWKS provides public keys for verification of tokens
+// @Summary JWKS
+// @Tags auth
+// @Description public keys for verification of tokens in JWK format
+// @Produce json
+// @Success 200 {object} service.JSONWebKeySet
+// @Router /.well-known/jwks.json [get]
+func (h *UserAuthHandler) JWKS(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.src.JWKS())
+}
+
+// SetUserRole provides logic for assigning role to user
+// @Summary SetUserRole
+// @Security ApiKeyAuth
+// @Tags auth
+// @Description assign role to user, it's allowed to admins only
+// @Accept json
+// @Produce json
+// @Param id path string true "id" format(uuid)
+// @Param role body request.UserRole true "role"
+// @Success 200 {object} models.User
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /admin/users/{id}/role [put]
+func (h *UserAuthHandler) SetUserRole(c echo.Context) error {
+	id, err := uuidParam(c, "id")
+	if err != nil {
+		return err
+	}
+	var input request.UserRole
+	if err = json.NewDecoder(c.Request().Body).Decode(&input); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err = c.Validate(input); err != nil {
+		return err
+	}
+
+	user, err := h.src.SetUserRoleServ(c.Request().Context(), id, input.Role)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, user)
+}
+
+// APIKeyResponse contains new API key, it's shown only once
+type APIKeyResponse struct {
+	Key    string        `json:"key" example:"cgk_Xh3kq9..."`
+	APIKey models.APIKey `json:"api_key"`
+}
+
+// CreateAPIKey provides logic for creating API key of user
+// @Summary CreateAPIKey
+// @Security ApiKeyAuth
+// @Tags auth
+// @Description create API key for machine clients, it's sent in X-API-Key header, the key is shown only once
+// @Accept json
+// @Produce json
+// @Param input body request.APIKeyCreate true "input"
+// @Success 201 {object} APIKeyResponse
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /me/api-keys [post]
+func (h *UserAuthHandler) CreateAPIKey(c echo.Context) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+	var input request.APIKeyCreate
+	if err := json.NewDecoder(c.Request().Body).Decode(&input); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+
+	key, apiKey, err := h.src.CreateAPIKeyServ(c.Request().Context(), claims.ID, input.Name, input.Scopes, input.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, APIKeyResponse{Key: key, APIKey: apiKey})
+}
+
+// ListAPIKeys provides logic for listing API keys of user
+// @Summary ListAPIKeys
+// @Security ApiKeyAuth
+// @Tags auth
+// @Description list API keys of user, keys themselves aren't shown
+// @Produce json
+// @Success 200 {array} models.APIKey
+// @Failure 401 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /me/api-keys [get]
+func (h *UserAuthHandler) ListAPIKeys(c echo.Context) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+	keys, err := h.src.ListAPIKeysServ(c.Request().Context(), claims.ID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey provides logic for revoking API key of user
+// @Summary RevokeAPIKey
+// @Security ApiKeyAuth
+// @Tags auth
+// @Description revoke API key of user
+// @Param id path string true "id" format(uuid)
+// @Success 204
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /me/api-keys/{id} [delete]
+func (h *UserAuthHandler) RevokeAPIKey(c echo.Context) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+	id, err := uuidParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	err = h.src.RevokeAPIKeyServ(c.Request().Context(), claims.ID, id)
+	if err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// TOTPEnrollResponse contains new TOTP secret, URI of it is shown as QR code to authenticator apps
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	URI    string `json:"uri" example:"otpauth://totp/CatsGo:steve?issuer=CatsGo&secret=JBSWY3DPEHPK3PXP"`
+}
+
+// RecoveryCodesResponse contains recovery codes, they are shown only once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// EnrollTOTP provides logic for enrollment of TOTP of user
+// @Summary EnrollTOTP
+// @Security ApiKeyAuth
+// @Tags auth
+// @Description generate TOTP secret, two-factor authentication is enabled after confirmation by code
+// @Produce json
+// @Success 200 {object} TOTPEnrollResponse
+// @Failure 401 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /me/mfa/totp [post]
+func (h *UserAuthHandler) EnrollTOTP(c echo.Context) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+	secret, uri, err := h.src.EnrollTOTPServ(c.Request().Context(), claims.ID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, TOTPEnrollResponse{Secret: secret, URI: uri})
+}
+
+// ConfirmTOTP provides logic for enabling TOTP of user
+// @Summary ConfirmTOTP
+// @Security ApiKeyAuth
+// @Tags auth
+// @Description enable two-factor authentication by code from authenticator app, recovery codes are shown only once
+// @Accept json
+// @Produce json
+// @Param input body request.MFACode true "input"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /me/mfa/totp/confirm [post]
+func (h *UserAuthHandler) ConfirmTOTP(c echo.Context) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+	var input request.MFACode
+	if err := json.NewDecoder(c.Request().Body).Decode(&input); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+
+	codes, err := h.src.ConfirmTOTPServ(c.Request().Context(), claims.ID, input.Code)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTOTP provides logic for disabling TOTP of user
+// @Summary DisableTOTP
+// @Security ApiKeyAuth
+// @Tags auth
+// @Description disable two-factor authentication by TOTP or recovery code
+// @Accept json
+// @Param input body request.MFACode true "input"
+// @Success 204
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /me/mfa/totp [delete]
+func (h *UserAuthHandler) DisableTOTP(c echo.Context) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+	var input request.MFACode
+	if err := json.NewDecoder(c.Request().Body).Decode(&input); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+
+	err := h.src.DisableTOTPServ(c.Request().Context(), claims.ID, input.Code)
+	if err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ForgotPassword provides logic for sending link for password reset
+// @Summary ForgotPassword
+// @Tags auth
+// @Description send single-use token for password reset to email, response doesn't tell whether email is known
+// @Accept json
+// @Param input body request.PasswordForgot true "input"
+// @Success 202
+// @Failure 400 {object} Problem
+// @Router /password/forgot [post]
+func (h *UserAuthHandler) ForgotPassword(c echo.Context) error {
+	var input request.PasswordForgot
+	if err := json.NewDecoder(c.Request().Body).Decode(&input); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+
+	h.src.ForgotPasswordServ(input.Email)
+	return c.NoContent(http.StatusAccepted)
+}
+
+// ResetPassword provides logic for password reset by token sent by email
+// @Summary ResetPassword
+// @Tags auth
+// @Description set new password by single-use token sent by email, all sessions of user are revoked
+// @Accept json
+// @Param input body request.PasswordReset true "input"
+// @Success 204
+// @Failure 400 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /password/reset [post]
+func (h *UserAuthHandler) ResetPassword(c echo.Context) error {
+	var input request.PasswordReset
+	if err := json.NewDecoder(c.Request().Body).Decode(&input); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+
+	err := h.src.ResetPasswordServ(c.Request().Context(), input.Token, input.Password)
+	if err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// VerifyEmail provides logic for verification of email by token sent on registration
+// @Summary VerifyEmail
+// @Tags auth
+// @Description verify email by single-use token sent by email
+// @Accept json
+// @Param input body request.EmailVerify true "input"
+// @Success 204
+// @Failure 400 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /email/verify [post]
+func (h *UserAuthHandler) VerifyEmail(c echo.Context) error {
+	var input request.EmailVerify
+	if err := json.NewDecoder(c.Request().Body).Decode(&input); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+
+	err := h.src.VerifyEmailServ(c.Request().Context(), input.Token)
+	if err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// SendVerification provides logic for sending link for verification of email again
+// @Summary SendVerification
+// @Security ApiKeyAuth
+// @Tags auth
+// @Description send single-use token for verification of email again
+// @Success 202
+// @Failure 401 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /me/email/verify [post]
+func (h *UserAuthHandler) SendVerification(c echo.Context) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+	err := h.src.SendVerificationServ(c.Request().Context(), claims.ID)
+	if err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusAccepted)
+}
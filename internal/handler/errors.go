@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"CatsGo/internal/apperror"
+	"CatsGo/internal/request"
+	"CatsGo/internal/service"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+// mimeProblemJSON is a content type of error responses
+const mimeProblemJSON = "application/problem+json"
+
+// Problem is a body of error response in format of RFC 7807
+type Problem struct {
+	Type     string `json:"type" example:"about:blank"`
+	Title    string `json:"title" example:"Not Found"`
+	Status   int    `json:"status" example:"404"`
+	Detail   string `json:"detail,omitempty" example:"cat doesn't exist in database"`
+	Instance string `json:"instance,omitempty" example:"/cats/9b2c4f5e-1f7a-4c53-9a43-8f4b0c7d2e11"`
+	// Errors lists invalid fields of request on validation errors
+	Errors []request.FieldError `json:"errors,omitempty"`
+}
+
+// kindStatuses maps kinds of errors of app to HTTP statuses
+var kindStatuses = map[apperror.Kind]int{
+	apperror.NotFound:        http.StatusNotFound,
+	apperror.Conflict:        http.StatusConflict,
+	apperror.Validation:      http.StatusBadRequest,
+	apperror.Unauthorized:    http.StatusUnauthorized,
+	apperror.Forbidden:       http.StatusForbidden,
+	apperror.TooManyRequests: http.StatusTooManyRequests,
+}
+
+// ErrorHandler is HTTPErrorHandler of echo, it sends errors of app and of echo as problem+json,
+// details of internal errors are logged and hidden from clients
+func ErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status, detail := http.StatusInternalServerError, ""
+	var (
+		appErr  *apperror.Error
+		httpErr *echo.HTTPError
+	)
+	switch {
+	case errors.As(err, &appErr) && appErr.Kind != apperror.Internal:
+		status, detail = kindStatuses[appErr.Kind], appErr.Error()
+	case errors.As(err, &httpErr):
+		status, detail = httpErr.Code, fmt.Sprint(httpErr.Message)
+	}
+	if status >= http.StatusInternalServerError {
+		log.Error(err)
+		detail = ""
+	}
+
+	var throttled *service.ThrottledError
+	if errors.As(err, &throttled) {
+		seconds := int64(math.Ceil(throttled.RetryAfter.Seconds()))
+		c.Response().Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	}
+
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.Request().URL.Path,
+	}
+	var validationErr *request.ValidationError
+	if errors.As(err, &validationErr) {
+		problem.Errors = validationErr.Fields(acceptLanguages(c.Request())...)
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		c.Response().Header().Set(echo.HeaderContentType, mimeProblemJSON)
+		err = c.JSON(status, problem)
+	}
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+// acceptLanguages returns languages from Accept-Language header of 'req' in order of preference,
+// regions are dropped since messages are translated by language only
+func acceptLanguages(req *http.Request) []string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+	var langs []weighted
+	for _, part := range strings.Split(req.Header.Get("Accept-Language"), ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang := strings.ToLower(strings.SplitN(fields[0], "-", 2)[0])
+		if lang == "" || lang == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if value := strings.TrimPrefix(param, "q="); value != param {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		langs = append(langs, weighted{lang: lang, q: q})
+	}
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+
+	result := make([]string, 0, len(langs))
+	for _, l := range langs {
+		result = append(result, l.lang)
+	}
+	return result
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"CatsGo/internal/repository"
+	"CatsGo/internal/request"
+	"CatsGo/internal/service"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorHandler(t *testing.T) {
+	TestTable := []struct {
+		name             string
+		inputErr         error
+		exceptStatusCode int
+		exceptDetail     string
+		exceptRetryAfter string
+	}{
+		{
+			name:             "not found",
+			inputErr:         fmt.Errorf("get cat: %w", repository.ErrCatNotFound),
+			exceptStatusCode: http.StatusNotFound,
+			exceptDetail:     repository.ErrCatNotFound.Error(),
+		},
+		{
+			name:             "conflict",
+			inputErr:         repository.ErrUsernameTaken,
+			exceptStatusCode: http.StatusConflict,
+			exceptDetail:     repository.ErrUsernameTaken.Error(),
+		},
+		{
+			name:             "validation",
+			inputErr:         repository.ErrInvalidCursor,
+			exceptStatusCode: http.StatusBadRequest,
+			exceptDetail:     repository.ErrInvalidCursor.Error(),
+		},
+		{
+			name:             "unauthorized",
+			inputErr:         service.ErrInvalidCredentials,
+			exceptStatusCode: http.StatusUnauthorized,
+			exceptDetail:     service.ErrInvalidCredentials.Error(),
+		},
+		{
+			name:             "forbidden",
+			inputErr:         service.ErrForbidden,
+			exceptStatusCode: http.StatusForbidden,
+			exceptDetail:     service.ErrForbidden.Error(),
+		},
+		{
+			name:             "throttled",
+			inputErr:         &service.ThrottledError{RetryAfter: 1500 * time.Millisecond},
+			exceptStatusCode: http.StatusTooManyRequests,
+			exceptDetail:     service.ErrTooManyAttempts.Error(),
+			exceptRetryAfter: "2",
+		},
+		{
+			name:             "error of echo",
+			inputErr:         echo.NewHTTPError(http.StatusBadRequest, "missing or malformed jwt"),
+			exceptStatusCode: http.StatusBadRequest,
+			exceptDetail:     "missing or malformed jwt",
+		},
+		{
+			name:             "internal error is hidden",
+			inputErr:         errors.New("connection refused"),
+			exceptStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/cats/1", nil)
+			rec := httptest.NewRecorder()
+
+			ErrorHandler(TestCase.inputErr, e.NewContext(req, rec))
+			assert.Equal(t, TestCase.exceptStatusCode, rec.Code)
+			assert.Equal(t, mimeProblemJSON, rec.Header().Get(echo.HeaderContentType))
+			assert.Equal(t, TestCase.exceptRetryAfter, rec.Header().Get("Retry-After"))
+
+			var problem Problem
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			assert.Equal(t, TestCase.exceptStatusCode, problem.Status)
+			assert.Equal(t, http.StatusText(TestCase.exceptStatusCode), problem.Title)
+			assert.Equal(t, TestCase.exceptDetail, problem.Detail)
+			assert.Equal(t, "/cats/1", problem.Instance)
+		})
+	}
+}
+
+func TestErrorHandler_Validation(t *testing.T) {
+	v, err := request.NewCustomValidator()
+	require.NoError(t, err)
+	e := echo.New()
+	e.Validator = v
+	e.HTTPErrorHandler = ErrorHandler
+	e.POST("/password/forgot", NewUserAuthHandler(nil).ForgotPassword)
+
+	req := httptest.NewRequest(http.MethodPost, "/password/forgot", strings.NewReader(`{"email":"steve"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("Accept-Language", "de-DE, ru;q=0.9, en;q=0.8")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var problem Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.Equal(t, []request.FieldError{
+		{Field: "email", Rule: "email", Message: "email должен быть email адресом"},
+	}, problem.Errors)
+}
+
+func TestAcceptLanguages(t *testing.T) {
+	TestTable := []struct {
+		name        string
+		inputHeader string
+		exceptLangs []string
+	}{
+		{name: "missing", inputHeader: "", exceptLangs: []string{}},
+		{name: "single", inputHeader: "ru-RU", exceptLangs: []string{"ru"}},
+		{name: "weighted", inputHeader: "en;q=0.5, ru-RU, *;q=0.1", exceptLangs: []string{"ru", "en"}},
+		{name: "same weight keeps order", inputHeader: "fr, en", exceptLangs: []string{"fr", "en"}},
+	}
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Language", TestCase.inputHeader)
+			assert.Equal(t, TestCase.exceptLangs, acceptLanguages(req))
+		})
+	}
+}
@@ -3,12 +3,19 @@ package handler
 
 import (
 	"CatsGo/internal/models"
+	"CatsGo/internal/request"
 	"CatsGo/internal/service"
 	"net/http"
+	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
-	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultCatsLimit = 20
+	headerTotalCount = "X-Total-Count"
+	headerNextCursor = "X-Next-Cursor"
 )
 
 // CatHandler init
@@ -21,43 +28,112 @@ func NewCatHandler(srv service.Service) *CatHandler {
 	return &CatHandler{src: srv}
 }
 
-// GetAllCats fetches all entities from cats collection
+// GetAllCats fetches a page of entities from cats collection
 // @Summary GetAllCats
 // @Tags Cats
-// @Description collect all cats in array
+// @Description collect a page of cats in array, total count of filtered cats and cursor of the next page are sent in headers
 // @Produce json
+// @Param limit query int false "page size" minimum(1) maximum(100) default(20)
+// @Param offset query int false "count of cats to skip, can't be combined with cursor" minimum(0)
+// @Param cursor query string false "opaque cursor of the next page from X-Next-Cursor header"
+// @Param sort query string false "sort order" Enums(name, -name, created_at, -created_at) default(created_at)
+// @Param name_prefix query string false "case-insensitive name prefix"
+// @Param name query string false "case-insensitive name substring"
 // @Success 200 {array} models.Cats
+// @Header 200 {integer} X-Total-Count "count of cats matching filters"
+// @Header 200 {string} X-Next-Cursor "cursor of the next page, missing on the last page"
+// @Failure 400 {object} Problem
 // @Router /cats [get]
 func (h *CatHandler) GetAllCats(c echo.Context) error {
-	allcats, err := h.src.GetAllCatsServ()
+	return h.listCats(c, nil)
+}
+
+// MyCats fetches a page of cats of authenticated user
+// @Summary MyCats
+// @Security ApiKeyAuth
+// @Tags Cats
+// @Description collect a page of cats owned by user, params and headers are the same as in GetAllCats
+// @Produce json
+// @Param limit query int false "page size" minimum(1) maximum(100) default(20)
+// @Param offset query int false "count of cats to skip, can't be combined with cursor" minimum(0)
+// @Param cursor query string false "opaque cursor of the next page from X-Next-Cursor header"
+// @Param sort query string false "sort order" Enums(name, -name, created_at, -created_at) default(created_at)
+// @Param name_prefix query string false "case-insensitive name prefix"
+// @Param name query string false "case-insensitive name substring"
+// @Success 200 {array} models.Cats
+// @Header 200 {integer} X-Total-Count "count of cats matching filters"
+// @Header 200 {string} X-Next-Cursor "cursor of the next page, missing on the last page"
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Router /me/cats [get]
+func (h *CatHandler) MyCats(c echo.Context) error {
+	actor, ok := tokenActor(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+	return h.listCats(c, &actor.ID)
+}
+
+// listCats sends a page of cats, only cats of 'ownerID' are listed if it's set
+func (h *CatHandler) listCats(c echo.Context, ownerID *uuid.UUID) error {
+	params := new(request.CatsList)
+	if err := c.Bind(params); err != nil {
+		return err
+	}
+	if err := c.Validate(params); err != nil {
+		return err
+	}
+	if params.Limit == 0 {
+		params.Limit = defaultCatsLimit
+	}
+
+	page, err := h.src.GetAllCatsServ(c.Request().Context(), models.CatsQuery{
+		Limit:        params.Limit,
+		Offset:       params.Offset,
+		Cursor:       params.Cursor,
+		Sort:         params.Sort,
+		NamePrefix:   params.NamePrefix,
+		NameContains: params.NameContains,
+		OwnerID:      ownerID,
+	})
 	if err != nil {
-		log.Error(err)
 		return err
 	}
-	return c.JSON(http.StatusOK, allcats)
+
+	c.Response().Header().Set(headerTotalCount, strconv.FormatInt(page.Total, 10))
+	if page.NextCursor != "" {
+		c.Response().Header().Set(headerNextCursor, page.NextCursor)
+	}
+	return c.JSON(http.StatusOK, page.Cats)
 }
 
 // CreateCat creates a new entity in cats collection
 // @Summary CreateCat
+// @Security ApiKeyAuth
 // @Tags Cats
-// @Description create cat
+// @Description create cat owned by user
 // @Accept json
 // @Produce json
 // @Param cats body models.Cats true "cats"
 // @Success 201 {object} models.Cats
-// @Failure 400 {object} models.Cats
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
 // @Router /cats [post]
 func (h *CatHandler) CreateCat(c echo.Context) error {
 	cats := new(models.Cats)
 	if err := c.Bind(cats); err != nil {
-		return c.JSON(http.StatusBadRequest, new(models.Cats))
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 	if err := c.Validate(cats); err != nil {
-		return c.JSON(http.StatusBadRequest, new(models.Cats))
+		return err
+	}
+	actor, ok := tokenActor(c)
+	if !ok {
+		return echo.ErrUnauthorized
 	}
-	cat, err := h.src.CreateCatServ(*cats)
+	cat, err := h.src.CreateCatServ(c.Request().Context(), actor, *cats)
 	if err != nil {
-		log.Error(err)
 		return err
 	}
 	return c.JSON(http.StatusCreated, cat)
@@ -69,73 +145,119 @@ func (h *CatHandler) CreateCat(c echo.Context) error {
 // @Description get cat by id
 // @Accept json
 // @Produce json
-// @Param id path uuid.UUID true "id"
+// @Param id path string true "id" format(uuid)
 // @Success 200 {object} models.Cats
-// @Failure 400 {object} models.Cats
-// @Failure 500 {string} string
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /cats/{id} [get]
 func (h *CatHandler) GetCat(c echo.Context) error {
-	id, _ := uuid.Parse(c.Param("id"))
-	cat, err := h.src.GetCatServ(id)
+	id, err := uuidParam(c, "id")
+	if err != nil {
+		return err
+	}
+	cat, err := h.src.GetCatServ(c.Request().Context(), id)
 	if err != nil {
-		log.Error(err)
-		return c.JSON(http.StatusNotFound, err.Error())
+		return err
 	}
 	return c.JSON(http.StatusOK, cat)
 }
 
 // UpdateCat updates a single cat in cats collection by 'id'
 // @Summary UpdateCat
+// @Security ApiKeyAuth
 // @Tags Cats
-// @Description update cat by id
+// @Description update cat by id, only owner or admin can do it
 // @Accept json
 // @Produce json
-// @Param id path uuid.UUID true "id"
+// @Param id path string true "id" format(uuid)
 // @Param cats body models.Cats true "cats"
 // @Success 200 {object} models.Cats
-// @Failure 400 {object} models.Cats
-// @Failure 500 {string} string
+// @Failure 400 {object} Problem
+// @Failure 500 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 404 {object} Problem
 // @Router /cats/{id} [put]
 func (h *CatHandler) UpdateCat(c echo.Context) error {
 	cats := new(models.Cats)
 	if err := c.Bind(cats); err != nil {
-		return c.JSON(http.StatusBadRequest, new(models.Cats))
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 	if err := c.Validate(cats); err != nil {
-		return c.JSON(http.StatusBadRequest, new(models.Cats))
+		return err
+	}
+	actor, ok := tokenActor(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+	id, err := uuidParam(c, "id")
+	if err != nil {
+		return err
 	}
-	id, _ := uuid.Parse(c.Param("id"))
-	cat, err := h.src.UpdateCatServ(id, *cats)
+	cat, err := h.src.UpdateCatServ(c.Request().Context(), actor, id, *cats)
 	if err != nil {
-		log.Error(err)
-		return c.JSON(http.StatusNotFound, err.Error())
+		return err
 	}
 	return c.JSON(http.StatusOK, cat)
 }
 
 // DeleteCat deletes a single cat from cats collection by 'id'
 // @Summary DeleteCat
+// @Security ApiKeyAuth
 // @Tags Cats
-// @Description delete cat by id
+// @Description delete cat by id, only owner or admin can do it
 // @Accept json
 // @Produce json
-// @Param id path uuid.UUID true "id"
+// @Param id path string true "id" format(uuid)
 // @Success 200 {object} models.Cats
-// @Failure 400 {object} models.Cats
-// @Failure 500 {string} string
+// @Failure 400 {object} Problem
+// @Failure 500 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 404 {object} Problem
 // @Router /cats/{id} [delete]
 func (h *CatHandler) DeleteCat(c echo.Context) error {
-	id, _ := uuid.Parse(c.Param("id"))
-	err := h.src.DeleteCatServ(id)
+	actor, ok := tokenActor(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+	id, err := uuidParam(c, "id")
+	if err != nil {
+		return err
+	}
+	err = h.src.DeleteCatServ(c.Request().Context(), actor, id)
 	if err != nil {
-		log.Error(err)
 		return err
 	}
 	return c.JSON(http.StatusOK, nil)
 }
 
-// RequestCatID struct init
-type RequestCatID struct {
-	ID   uuid.UUID `json:"id" bson:"id"`
-	Name string    `json:"name" bson:"name"`
+// SearchCats looks for cats with names matching query, tolerating typos
+// @Summary SearchCats
+// @Tags Cats
+// @Description full-text and fuzzy search of cats by name, best matches go first
+// @Produce json
+// @Param q query string true "search query" minlength(2) maxlength(120)
+// @Param limit query int false "max count of cats" minimum(1) maximum(100) default(20)
+// @Success 200 {array} models.Cats
+// @Failure 400 {object} Problem
+// @Router /cats/search [get]
+func (h *CatHandler) SearchCats(c echo.Context) error {
+	params := new(request.CatsSearch)
+	if err := c.Bind(params); err != nil {
+		return err
+	}
+	if err := c.Validate(params); err != nil {
+		return err
+	}
+	if params.Limit == 0 {
+		params.Limit = defaultCatsLimit
+	}
+
+	allcats, err := h.src.SearchCatsServ(c.Request().Context(), params.Query, params.Limit)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, allcats)
 }
@@ -8,17 +8,128 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// tokenClaims returns claims of token set by JWT middleware
+func tokenClaims(c echo.Context) (*service.JwtCustomClaims, bool) {
+	token, ok := c.Get("user").(*jwt.Token)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := token.Claims.(*service.JwtCustomClaims)
+	return claims, ok
+}
+
+// tokenActor returns user authenticated by JWT middleware
+func tokenActor(c echo.Context) (service.Actor, bool) {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return service.Actor{}, false
+	}
+	return service.Actor{ID: claims.ID, Role: claims.Role}, true
+}
+
+// headerAPIKey is a header with API key, it's used instead of Authorization one
+const headerAPIKey = "X-API-Key"
+
+// HasAPIKey reports whether request is authenticated by API key, it's a skipper of JWT middleware
+func HasAPIKey(c echo.Context) bool {
+	return c.Request().Header.Get(headerAPIKey) != ""
+}
+
+// APIKeyAuth authenticates requests with API key and sets claims of its owner like JWT middleware does,
+// it goes before JWT middleware skipping such requests
+func (h *UserAuthHandler) APIKeyAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key := c.Request().Header.Get(headerAPIKey)
+		if key == "" {
+			return next(c)
+		}
+		claims, err := h.src.AuthenticateAPIKey(c.Request().Context(), key)
+		if err != nil {
+			return err
+		}
+		c.Set("user", &jwt.Token{Claims: claims, Valid: true})
+		return next(c)
+	}
+}
+
+// RequireScope allows requests made with API keys only if key has 'scope', it goes after AccessTokenOnly
+func RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, ok := tokenClaims(c)
+			if !ok {
+				return echo.ErrUnauthorized
+			}
+			if claims.Type != service.TokenTypeAPIKey {
+				return next(c)
+			}
+			for _, granted := range claims.Scopes {
+				if granted == scope {
+					return next(c)
+				}
+			}
+			return echo.NewHTTPError(http.StatusForbidden, "api key has no scope "+scope)
+		}
+	}
+}
+
+// AccessTokenOnly rejects tokens other than access ones and API keys, it goes after JWT middleware
+func AccessTokenOnly(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		claims, ok := tokenClaims(c)
+		if !ok || claims.Type != service.TokenTypeAccess && claims.Type != service.TokenTypeAPIKey {
+			return echo.NewHTTPError(http.StatusUnauthorized, "access token is required")
+		}
+		return next(c)
+	}
+}
+
+// CheckToken rejects access tokens revoked by logout, it goes after AccessTokenOnly
+func (h *UserAuthHandler) CheckToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		claims, ok := tokenClaims(c)
+		if !ok {
+			return echo.ErrUnauthorized
+		}
+		err := h.src.CheckToken(c.Request().Context(), claims)
+		if err != nil {
+			return err
+		}
+		return next(c)
+	}
+}
+
+// RequireRole allows requests only to users with one of 'roles', it goes after CheckToken
+func RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, ok := tokenClaims(c)
+			if !ok {
+				return echo.ErrUnauthorized
+			}
+			for _, role := range roles {
+				if claims.Role == role {
+					return next(c)
+				}
+			}
+			return echo.NewHTTPError(http.StatusForbidden, "insufficient role")
+		}
+	}
+}
+
 // Restricted provides access to hidden page for authorized users
 // @Summary Restricted
 // @Security ApiKeyAuth
 // @Description example closed page
 // @Produce json
 // @Success 200 {string} string
-// @Failure 400 {object} string
+// @Failure 400 {object} Problem
 // @Router /restrict [get]
 func (h *UserAuthHandler) Restricted(c echo.Context) error {
-	user := c.Get("user").(*jwt.Token)
-	claims := user.Claims.(*service.JwtCustomClaims)
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
 	name := claims.Name
 	return c.String(http.StatusOK, "Welcome "+name)
 }
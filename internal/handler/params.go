@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"CatsGo/internal/apperror"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// ParamParser parses and validates raw value of path param, the error is shown to clients
+type ParamParser func(raw string) (interface{}, error)
+
+// errInvalidUUID is returned by ParseUUID
+var errInvalidUUID = apperror.New(apperror.Validation, "must be a valid UUID")
+
+// ParseUUID is ParamParser of UUIDs in canonical form
+func ParseUUID(raw string) (interface{}, error) {
+	id, err := uuid.Parse(raw)
+	if err != nil || len(raw) != len(uuid.Nil.String()) {
+		return nil, errInvalidUUID
+	}
+	return id, nil
+}
+
+// paramKey is a key of context keeping path param 'name' parsed by PathParam
+func paramKey(name string) string {
+	return "param:" + name
+}
+
+// PathParam parses path param 'name' by 'parse' and keeps the value in context,
+// malformed params are rejected with 400 before handler is called
+func PathParam(name string, parse ParamParser) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			value, err := parse(c.Param(name))
+			if err != nil {
+				return invalidParam(name, err)
+			}
+			c.Set(paramKey(name), value)
+			return next(c)
+		}
+	}
+}
+
+// UUIDParam is PathParam of UUIDs
+func UUIDParam(name string) echo.MiddlewareFunc {
+	return PathParam(name, ParseUUID)
+}
+
+// uuidParam returns path param 'name' parsed by UUIDParam, the param is parsed here if middleware is missing
+func uuidParam(c echo.Context, name string) (uuid.UUID, error) {
+	if id, ok := c.Get(paramKey(name)).(uuid.UUID); ok {
+		return id, nil
+	}
+	value, err := ParseUUID(c.Param(name))
+	if err != nil {
+		return uuid.Nil, invalidParam(name, err)
+	}
+	return value.(uuid.UUID), nil
+}
+
+// invalidParam returns validation error of path param 'name' rejected by parser
+func invalidParam(name string, err error) error {
+	return apperror.New(apperror.Validation, "path param "+name+" "+err.Error())
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"CatsGo/internal/apperror"
+	"CatsGo/internal/models"
+	"CatsGo/internal/repository"
+	"CatsGo/internal/service"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatHandler_GetCat_IDParam(t *testing.T) {
+	rps := repository.NewMemoryRepository()
+	cat, err := rps.CreateCat(context.Background(), models.Cats{Name: "Barsik"})
+	require.NoError(t, err)
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler
+	e.GET("/cats/:id", NewCatHandler(service.NewCatService(rps)).GetCat, UUIDParam("id"))
+
+	TestTable := []struct {
+		name             string
+		inputID          string
+		exceptStatusCode int
+		exceptDetail     string
+	}{
+		{
+			name:             "OK",
+			inputID:          cat.ID.String(),
+			exceptStatusCode: http.StatusOK,
+		},
+		{
+			name:             "missing cat",
+			inputID:          uuid.NewString(),
+			exceptStatusCode: http.StatusNotFound,
+			exceptDetail:     repository.ErrCatNotFound.Error(),
+		},
+		{
+			name:             "malformed id",
+			inputID:          "barsik",
+			exceptStatusCode: http.StatusBadRequest,
+			exceptDetail:     "path param id must be a valid UUID",
+		},
+		{
+			name:             "id isn't in canonical form",
+			inputID:          strings.ReplaceAll(cat.ID.String(), "-", ""),
+			exceptStatusCode: http.StatusBadRequest,
+			exceptDetail:     "path param id must be a valid UUID",
+		},
+	}
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/cats/"+TestCase.inputID, nil))
+			assert.Equal(t, TestCase.exceptStatusCode, rec.Code)
+			if TestCase.exceptStatusCode == http.StatusOK {
+				return
+			}
+			var problem Problem
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			assert.Equal(t, TestCase.exceptDetail, problem.Detail)
+		})
+	}
+}
+
+func TestUUIDParam_WithoutMiddleware(t *testing.T) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	c.SetParamNames("id")
+	c.SetParamValues("barsik")
+
+	_, err := uuidParam(c, "id")
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+
+	id := uuid.New()
+	c.SetParamValues(id.String())
+	parsed, err := uuidParam(c, "id")
+	require.NoError(t, err)
+	assert.Equal(t, id, parsed)
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"CatsGo/internal/configs"
+	"CatsGo/internal/mailer"
+	"CatsGo/internal/repository"
+	"CatsGo/internal/request"
+	"CatsGo/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAuthService returns auth service keeping users in memory and keys signing its tokens,
+// emails of it are written to log
+func newAuthService(t *testing.T) (*service.UserAuthService, *service.KeySet) {
+	cfg := &configs.Config{KeyForSignatureJwt: "test", Argon2Memory: 1024, Argon2Time: 1, Argon2Threads: 1}
+	keys, err := service.NewKeySet(cfg)
+	require.NoError(t, err)
+	srv, err := service.NewUserAuthService(repository.NewMemoryRepository(),
+		repository.NewMemoryTokenStore(), repository.NewMemoryLoginAttempts(), mailer.NewLogMailer(), keys, cfg)
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+	return srv, keys
+}
+
+func TestUserAuthHandler_SignUp_Conflict(t *testing.T) {
+	srv, _ := newAuthService(t)
+	h := NewUserAuthHandler(srv)
+
+	TestTable := []struct {
+		name             string
+		inputJSON        string
+		exceptStatusCode int
+	}{
+		{
+			name:             "OK",
+			inputJSON:        `{"name":"Steve Jobs","username":"steve","password":"Stev13jb7","email":"steve@example.com"}`,
+			exceptStatusCode: http.StatusOK,
+		},
+		{
+			name:             "username is taken",
+			inputJSON:        `{"name":"Steve Wozniak","username":"steve","password":"Stev13jb7","email":"woz@example.com"}`,
+			exceptStatusCode: http.StatusConflict,
+		},
+		{
+			name:             "email is taken",
+			inputJSON:        `{"name":"Steve Wozniak","username":"woz1","password":"Stev13jb7","email":"STEVE@example.com"}`,
+			exceptStatusCode: http.StatusConflict,
+		},
+	}
+
+	v, err := request.NewCustomValidator()
+	require.NoError(t, err)
+	e := echo.New()
+	e.Validator = v
+	e.HTTPErrorHandler = ErrorHandler
+	e.POST("/register", h.SignUp)
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(TestCase.inputJSON))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, TestCase.exceptStatusCode, rec.Code)
+		})
+	}
+}
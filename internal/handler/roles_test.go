@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"CatsGo/internal/models"
+	"CatsGo/internal/repository"
+	"CatsGo/internal/request"
+	"CatsGo/internal/service"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireRole(t *testing.T) {
+	TestTable := []struct {
+		name             string
+		claims           *service.JwtCustomClaims
+		exceptStatusCode int
+	}{
+		{
+			name:             "admin",
+			claims:           &service.JwtCustomClaims{Type: service.TokenTypeAccess, Role: models.RoleAdmin},
+			exceptStatusCode: http.StatusOK,
+		},
+		{
+			name:             "staff",
+			claims:           &service.JwtCustomClaims{Type: service.TokenTypeAccess, Role: models.RoleStaff},
+			exceptStatusCode: http.StatusOK,
+		},
+		{
+			name:             "viewer",
+			claims:           &service.JwtCustomClaims{Type: service.TokenTypeAccess, Role: models.RoleViewer},
+			exceptStatusCode: http.StatusForbidden,
+		},
+		{
+			name:             "refresh token",
+			claims:           &service.JwtCustomClaims{Type: service.TokenTypeRefresh, Role: models.RoleAdmin},
+			exceptStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:             "anonymous",
+			exceptStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodPost, "/cats", nil), rec)
+			if TestCase.claims != nil {
+				c.Set("user", &jwt.Token{Claims: TestCase.claims})
+			}
+
+			h := AccessTokenOnly(RequireRole(models.RoleStaff, models.RoleAdmin)(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}))
+			err := h(c)
+			if TestCase.exceptStatusCode == http.StatusOK {
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusOK, rec.Code)
+				return
+			}
+			var httpErr *echo.HTTPError
+			if assert.ErrorAs(t, err, &httpErr) {
+				assert.Equal(t, TestCase.exceptStatusCode, httpErr.Code)
+			}
+		})
+	}
+}
+
+func TestCatHandler_CreateCat_Roles(t *testing.T) {
+	ctx := context.Background()
+	srvAuth, keys := newAuthService(t)
+
+	// route is protected the same way as in main
+	v, err := request.NewCustomValidator()
+	require.NoError(t, err)
+	e := echo.New()
+	e.Validator = v
+	jwtConfig := middleware.JWTConfig{
+		ParseTokenFunc: func(auth string, c echo.Context) (interface{}, error) {
+			return service.VerifyToken(auth, keys)
+		},
+	}
+	e.POST("/cats", NewCatHandler(service.NewCatService(repository.NewMemoryRepository())).CreateCat,
+		middleware.JWTWithConfig(jwtConfig), AccessTokenOnly, NewUserAuthHandler(srvAuth).CheckToken,
+		RequireScope(models.ScopeCatsWrite), RequireRole(models.RoleStaff, models.RoleAdmin))
+
+	// login returns access token of user with 'role'
+	login := func(username, role string) string {
+		user, err := srvAuth.CreateUserServ(ctx, models.User{Name: "Steve Jobs", Username: username, Password: "Stev13jb7"})
+		require.NoError(t, err)
+		_, err = srvAuth.SetUserRoleServ(ctx, user.ID, role)
+		require.NoError(t, err)
+		token, _, _, err := srvAuth.GenerateToken(ctx, username, "Stev13jb7", "")
+		require.NoError(t, err)
+		return token
+	}
+
+	TestTable := []struct {
+		name             string
+		token            string
+		exceptStatusCode int
+	}{
+		{
+			name:             "viewer",
+			token:            login("viewer", models.RoleViewer),
+			exceptStatusCode: http.StatusForbidden,
+		},
+		{
+			name:             "staff",
+			token:            login("staff", models.RoleStaff),
+			exceptStatusCode: http.StatusCreated,
+		},
+		{
+			name:             "admin",
+			token:            login("admin", models.RoleAdmin),
+			exceptStatusCode: http.StatusCreated,
+		},
+	}
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/cats", strings.NewReader(`{"name":"Barsik"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+TestCase.token)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, TestCase.exceptStatusCode, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	TestTable := []struct {
+		name             string
+		claims           *service.JwtCustomClaims
+		exceptStatusCode int
+	}{
+		{
+			name:             "access token",
+			claims:           &service.JwtCustomClaims{Type: service.TokenTypeAccess},
+			exceptStatusCode: http.StatusOK,
+		},
+		{
+			name: "api key with scope",
+			claims: &service.JwtCustomClaims{Type: service.TokenTypeAPIKey,
+				Scopes: []string{models.ScopeCatsRead, models.ScopeCatsWrite}},
+			exceptStatusCode: http.StatusOK,
+		},
+		{
+			name:             "api key without scope",
+			claims:           &service.JwtCustomClaims{Type: service.TokenTypeAPIKey, Scopes: []string{models.ScopeCatsRead}},
+			exceptStatusCode: http.StatusForbidden,
+		},
+		{
+			name:             "anonymous",
+			exceptStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodPost, "/cats", nil), rec)
+			if TestCase.claims != nil {
+				c.Set("user", &jwt.Token{Claims: TestCase.claims})
+			}
+
+			h := AccessTokenOnly(RequireScope(models.ScopeCatsWrite)(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}))
+			err := h(c)
+			if TestCase.exceptStatusCode == http.StatusOK {
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusOK, rec.Code)
+				return
+			}
+			var httpErr *echo.HTTPError
+			if assert.ErrorAs(t, err, &httpErr) {
+				assert.Equal(t, TestCase.exceptStatusCode, httpErr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// FileMailer saves emails as .eml files in directory, it's used for local development
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer is constructor, 'dir' is created if it's missing
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+// Send saves 'msg' in a new file named by time of sending
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	now := time.Now()
+	data, err := encode(m.from, msg, now)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405"), uuid.NewString()[:8])
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0o600)
+}
+
+// LogMailer writes recipient and subject of emails to log instead of sending them, it's used for local development.
+// Body isn't logged since it carries tokens of users
+type LogMailer struct{}
+
+// NewLogMailer is constructor
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send writes recipient and subject of 'msg' to log
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	log.WithFields(log.Fields{"to": msg.To, "subject": msg.Subject}).Info("email isn't sent, mailer is log")
+	return nil
+}
@@ -0,0 +1,64 @@
+// Package mailer provides delivery of emails to users
+package mailer
+
+import (
+	"CatsGo/internal/configs"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+)
+
+// errHeaderInjection is returned when recipient or subject contains line breaks
+var errHeaderInjection = errors.New("line breaks aren't allowed in headers of email")
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns mailer selected by MAILER: smtp, file or log
+func New(cfg *configs.Config) (Mailer, error) {
+	switch cfg.Mailer {
+	case "smtp", "":
+		return NewSMTPMailer(cfg), nil
+	case "file":
+		return NewFileMailer(cfg.MailDir, cfg.MailFrom)
+	case "log":
+		return NewLogMailer(), nil
+	}
+	return nil, fmt.Errorf("unknown mailer %q, expected smtp / file / log", cfg.Mailer)
+}
+
+// encode formats 'msg' from 'from' as RFC 5322 email with CRLF line endings
+func encode(from string, msg Message, date time.Time) ([]byte, error) {
+	for _, header := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, errHeaderInjection
+		}
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	body := strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n")
+	buf.WriteString(body)
+	if !strings.HasSuffix(body, "\r\n") {
+		buf.WriteString("\r\n")
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,152 @@
+package mailer
+
+import (
+	"CatsGo/internal/configs"
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMessage = Message{To: "steve@example.com", Subject: "Reset password", Body: "token: 123\nbye"}
+
+func TestEncode(t *testing.T) {
+	data, err := encode("CatsGo <noreply@catsgo.local>", testMessage, time.Unix(0, 0))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "To: steve@example.com\r\n")
+	assert.Contains(t, string(data), "Subject: Reset password\r\n")
+	assert.True(t, strings.HasSuffix(string(data), "\r\n\r\ntoken: 123\r\nbye\r\n"))
+
+	TestTable := []struct {
+		name string
+		msg  Message
+	}{
+		{name: "recipient", msg: Message{To: "steve@example.com\r\nBcc: eve@example.com", Subject: "hi"}},
+		{name: "subject", msg: Message{To: "steve@example.com", Subject: "hi\nBcc: eve@example.com"}},
+	}
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			_, err := encode("noreply@catsgo.local", TestCase.msg, time.Now())
+			assert.ErrorIs(t, err, errHeaderInjection)
+		})
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := NewFileMailer(dir, "noreply@catsgo.local")
+	require.NoError(t, err)
+	require.NoError(t, m.Send(context.Background(), testMessage))
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "token: 123")
+}
+
+// fakeSMTP accepts a single email and sends its envelope and data to channel
+func fakeSMTP(t *testing.T) (addr string, received chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	received = make(chan string, 1)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+		reply := func(line string) {
+			w.WriteString(line + "\r\n")
+			w.Flush()
+		}
+		var session strings.Builder
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 fake")
+			case strings.HasPrefix(command, "MAIL"), strings.HasPrefix(command, "RCPT"):
+				session.WriteString(strings.TrimSpace(line) + "\n")
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 go ahead")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					session.WriteString(line)
+				}
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 bye")
+				received <- session.String()
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return ln.Addr().String(), received
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, received := fakeSMTP(t)
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+	m := NewSMTPMailer(&configs.Config{SMTPHost: host, SMTPPort: port, MailFrom: "CatsGo <noreply@catsgo.local>"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, m.Send(ctx, testMessage))
+
+	select {
+	case session := <-received:
+		assert.Contains(t, session, "MAIL FROM:<noreply@catsgo.local>")
+		assert.Contains(t, session, "RCPT TO:<steve@example.com>")
+		assert.Contains(t, session, "token: 123")
+	case <-ctx.Done():
+		t.Fatal("email isn't received")
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&configs.Config{Mailer: "pigeon"})
+	assert.Error(t, err)
+	m, err := New(&configs.Config{Mailer: "log"})
+	require.NoError(t, err)
+	assert.IsType(t, &LogMailer{}, m)
+	m, err = New(&configs.Config{})
+	require.NoError(t, err)
+	assert.IsType(t, &SMTPMailer{}, m)
+}
+
+func TestLogMailer(t *testing.T) {
+	hook := logtest.NewGlobal()
+	t.Cleanup(hook.Reset)
+	require.NoError(t, NewLogMailer().Send(context.Background(), testMessage))
+
+	require.Len(t, hook.AllEntries(), 1)
+	entry := hook.LastEntry()
+	assert.Equal(t, "steve@example.com", entry.Data["to"])
+	assert.Equal(t, "Reset password", entry.Data["subject"])
+	assert.NotContains(t, entry.Message, "123")
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// DateLayout is a format of dates in JSON
+const DateLayout = "2006-01-02"
+
+// Date is a calendar day without time of day, it's midnight of UTC inside
+type Date struct {
+	time.Time
+}
+
+// NewDate returns day of t in its location
+func NewDate(t time.Time) Date {
+	return Date{Time: time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+// String returns date in DateLayout
+func (d Date) String() string {
+	return d.Format(DateLayout)
+}
+
+// MarshalJSON encodes date as a string in DateLayout
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON decodes date from a string in DateLayout
+func (d *Date) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return err
+	}
+	*d = Date{Time: t}
+	return nil
+}
+
+// MarshalBSONValue stores date as BSON datetime
+func (d Date) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bson.MarshalValue(d.Time)
+}
+
+// UnmarshalBSONValue reads date from BSON datetime
+func (d *Date) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	var value time.Time
+	if err := (bson.RawValue{Type: t, Value: data}).Unmarshal(&value); err != nil {
+		return err
+	}
+	*d = NewDate(value.UTC())
+	return nil
+}
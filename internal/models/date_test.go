@@ -0,0 +1,68 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestDate_JSON(t *testing.T) {
+	TestTable := []struct {
+		name        string
+		inputJSON   string
+		exceptDate  *Date
+		exceptError bool
+	}{
+		{
+			name:       "date",
+			inputJSON:  `{"name":"Barsik","birth_date":"2020-05-01"}`,
+			exceptDate: &Date{Time: time.Date(2020, time.May, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:      "null",
+			inputJSON: `{"name":"Barsik","birth_date":null}`,
+		},
+		{
+			name:        "timestamp",
+			inputJSON:   `{"name":"Barsik","birth_date":"2020-05-01T10:00:00Z"}`,
+			exceptError: true,
+		},
+		{
+			name:        "not a string",
+			inputJSON:   `{"name":"Barsik","birth_date":20200501}`,
+			exceptError: true,
+		},
+	}
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			var cat Cats
+			err := json.Unmarshal([]byte(TestCase.inputJSON), &cat)
+			if TestCase.exceptError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, TestCase.exceptDate, cat.BirthDate)
+		})
+	}
+}
+
+func TestDate_RoundTrip(t *testing.T) {
+	date := NewDate(time.Date(2020, time.May, 1, 23, 30, 0, 0, time.FixedZone("UTC+3", 3*60*60)))
+	cat := Cats{Name: "Barsik", BirthDate: &date}
+
+	data, err := json.Marshal(cat)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"birth_date":"2020-05-01"`)
+
+	data, err = bson.Marshal(cat)
+	require.NoError(t, err)
+	var decoded Cats
+	require.NoError(t, bson.Unmarshal(data, &decoded))
+	assert.Equal(t, cat.BirthDate, decoded.BirthDate)
+}
@@ -2,19 +2,114 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
+// Sort orders supported by cats listing, '-' prefix means descending order
+const (
+	SortByName          = "name"
+	SortByNameDesc      = "-name"
+	SortByCreatedAt     = "created_at"
+	SortByCreatedAtDesc = "-created_at"
+)
+
+// Sex of cats, empty one means it's unknown
+const (
+	CatSexMale   = "male"
+	CatSexFemale = "female"
+)
+
+// Statuses of cats
+const (
+	CatStatusAvailable = "available"
+	CatStatusAdopted   = "adopted"
+	CatStatusDeceased  = "deceased"
+)
+
+// Roles of users
+const (
+	RoleAdmin  = "admin"
+	RoleStaff  = "staff"
+	RoleViewer = "viewer"
+)
+
 // Cats contains all related data to cats in database
 type Cats struct {
-	ID   uuid.UUID `json:"id" bson:"id"`
-	Name string    `json:"name" bson:"name" validate:"required,min=3"`
+	ID        uuid.UUID `json:"id" bson:"id"`
+	Name      string    `json:"name" bson:"name" validate:"required,min=3,max=120" example:"Barsik"`
+	Breed     string    `json:"breed,omitempty" bson:"breed" validate:"max=120" example:"Siberian"`
+	BirthDate *Date     `json:"birth_date,omitempty" bson:"birth_date,omitempty" validate:"omitempty,lte" swaggertype:"string" format:"date" example:"2020-05-01"`
+	Sex       string    `json:"sex,omitempty" bson:"sex" validate:"omitempty,oneof=male female" enums:"male,female"`
+	Color     string    `json:"color,omitempty" bson:"color" validate:"max=60" example:"tabby"`
+	// Weight in kilograms
+	Weight float64 `json:"weight,omitempty" bson:"weight" validate:"gte=0,lte=30" example:"4.5"`
+	// Status is 'available' by default
+	Status string `json:"status" bson:"status" validate:"omitempty,oneof=available adopted deceased" enums:"available,adopted,deceased"`
+	// OwnerID is an id of user who created cat, it's missing for cats created before ownership
+	OwnerID   *uuid.UUID `json:"owner_id,omitempty" bson:"owner_id,omitempty" swaggertype:"string" format:"uuid" readonly:"true"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" bson:"updated_at"`
+}
+
+// CatsQuery contains params of cats listing, Cursor takes precedence over Offset
+type CatsQuery struct {
+	Limit        int
+	Offset       int
+	Cursor       string
+	Sort         string
+	NamePrefix   string
+	NameContains string
+	// OwnerID limits listing to cats of one user
+	OwnerID *uuid.UUID
+}
+
+// CatsPage contains a single page of cats listing
+type CatsPage struct {
+	Cats []*Cats
+	// Total is a count of cats matching filters of query
+	Total int64
+	// NextCursor points to the next page, it's empty on the last page
+	NextCursor string
 }
 
 // User contains all related data to user in database
 type User struct {
-	ID       uuid.UUID `json:"id"`
-	Name     string    `json:"name" validate:"required,min=3"`
-	Username string    `json:"username" validate:"required,lowercase,min=4"`
-	Password string    `json:"password" validate:"required,max=20,min=6"`
+	ID       uuid.UUID `json:"id" bson:"id"`
+	Name     string    `json:"name" bson:"name" validate:"required,min=3"`
+	Username string    `json:"username" bson:"username" validate:"required,lowercase,min=4"`
+	Password string    `json:"password" bson:"password" validate:"required,max=20,min=6"`
+	Email    string    `json:"email" bson:"email,omitempty" validate:"required,email,max=254" example:"steve@example.com"`
+	// EmailVerified is set when user follows link sent to email
+	EmailVerified bool `json:"email_verified" bson:"email_verified" readonly:"true"`
+	// Role is assigned by admins, new users are viewers
+	Role string `json:"role,omitempty" bson:"role" enums:"admin,staff,viewer"`
+	// TOTPSecret is a base32 secret of TOTP, it's checked on login only when TOTPEnabled
+	TOTPSecret  string `json:"-" bson:"totp_secret"`
+	TOTPEnabled bool   `json:"-" bson:"totp_enabled"`
+	// TOTPLastCounter is a time step of the last accepted TOTP code, codes of it and earlier steps are rejected
+	TOTPLastCounter int64 `json:"-" bson:"totp_last_counter"`
+	// RecoveryCodes are SHA-256 hashes of unused recovery codes
+	RecoveryCodes []string `json:"-" bson:"recovery_codes"`
+}
+
+// Scopes of API keys
+const (
+	ScopeCatsRead  = "cats:read"
+	ScopeCatsWrite = "cats:write"
+)
+
+// APIKey is a personal key of user for machine clients, the key itself is shown once on creation
+type APIKey struct {
+	ID     uuid.UUID `json:"id" bson:"id"`
+	UserID uuid.UUID `json:"-" bson:"user_id"`
+	Name   string    `json:"name" bson:"name" example:"backup script"`
+	// Prefix is a beginning of key to tell keys apart
+	Prefix string `json:"prefix" bson:"prefix" example:"cgk_Xh3k"`
+	// Hash is SHA-256 of key
+	Hash      string     `json:"-" bson:"hash"`
+	Scopes    []string   `json:"scopes" bson:"scopes" enums:"cats:read,cats:write"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
 }
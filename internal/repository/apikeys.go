@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"CatsGo/internal/apperror"
+	"CatsGo/internal/models"
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrAPIKeyNotFound is returned when API key is missing in database
+var ErrAPIKeyNotFound = apperror.New(apperror.NotFound, "api key doesn't exist in database")
+
+// apiKeyColumns lists columns of api_keys table in order of scanAPIKey
+const apiKeyColumns = "id, user_id, name, prefix, hash, scopes, expires_at, created_at"
+
+// scanAPIKey reads a row of apiKeyColumns into key
+func scanAPIKey(row pgx.Row, key *models.APIKey) error {
+	return row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.Hash, &key.Scopes, &key.ExpiresAt,
+		&key.CreatedAt)
+}
+
+// CreateAPIKey saves new API key of user in pgdb
+func (c *PostgresRepository) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
+
+	key.ID = uuid.New()
+	err := c.conn.QueryRow(ctx, "INSERT INTO api_keys (id, user_id, name, prefix, hash, scopes, expires_at) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at",
+		key.ID, key.UserID, key.Name, key.Prefix, key.Hash, key.Scopes, key.ExpiresAt).Scan(&key.CreatedAt)
+	if err != nil {
+		log.Error(err)
+		return models.APIKey{}, err
+	}
+	return key, nil
+}
+
+// ListAPIKeys returns API keys of user from pgdb, oldest first
+func (c *PostgresRepository) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
+
+	rows, err := c.conn.Query(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE user_id = $1 "+
+		"ORDER BY created_at, id", userID)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]models.APIKey, 0)
+	for rows.Next() {
+		var key models.APIKey
+		if err := scanAPIKey(rows, &key); err != nil {
+			log.Error(err)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+// GetAPIKeyByHash returns API key by SHA-256 'hash' of it from pgdb
+func (c *PostgresRepository) GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
+
+	var key models.APIKey
+	err := scanAPIKey(c.conn.QueryRow(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE hash = $1", hash), &key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.APIKey{}, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		log.Error(err)
+		return models.APIKey{}, err
+	}
+	return key, nil
+}
+
+// DeleteAPIKey deletes API key 'id' of user from pgdb
+func (c *PostgresRepository) DeleteAPIKey(ctx context.Context, userID, id uuid.UUID) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
+
+	tag, err := c.conn.Exec(ctx, "DELETE FROM api_keys WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// apiKeys returns collection of API keys in mongodb
+func (c *MongoRepository) apiKeys() *mongo.Collection {
+	return c.client.Database(c.cfg.MongoDBName).Collection(c.cfg.MongoAPIKeysCollection)
+}
+
+// createAPIKeysIndex creates unique index on hashes of API keys and index on their owners
+func (c *MongoRepository) createAPIKeysIndex(ctx context.Context) error {
+	_, err := c.apiKeys().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{primitive.E{Key: "hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "id", Value: 1}},
+		},
+	})
+	return err
+}
+
+// CreateAPIKey saves new API key of user in mongodb
+func (c *MongoRepository) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.MongoTimeout)
+	defer cancel()
+
+	key.ID, key.CreatedAt = uuid.New(), time.Now().UTC().Truncate(time.Millisecond)
+	if _, err := c.apiKeys().InsertOne(ctx, key); err != nil {
+		log.Error(err)
+		return models.APIKey{}, err
+	}
+	return key, nil
+}
+
+// ListAPIKeys returns API keys of user from mongodb, oldest first
+func (c *MongoRepository) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.MongoTimeout)
+	defer cancel()
+
+	cursor, err := c.apiKeys().Find(ctx, bson.D{primitive.E{Key: "user_id", Value: userID}},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}}))
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	keys := make([]models.APIKey, 0)
+	if err := cursor.All(ctx, &keys); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+// GetAPIKeyByHash returns API key by SHA-256 'hash' of it from mongodb
+func (c *MongoRepository) GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.MongoTimeout)
+	defer cancel()
+
+	var key models.APIKey
+	err := c.apiKeys().FindOne(ctx, bson.D{primitive.E{Key: "hash", Value: hash}}).Decode(&key)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.APIKey{}, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		log.Error(err)
+		return models.APIKey{}, err
+	}
+	return key, nil
+}
+
+// DeleteAPIKey deletes API key 'id' of user from mongodb
+func (c *MongoRepository) DeleteAPIKey(ctx context.Context, userID, id uuid.UUID) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.MongoTimeout)
+	defer cancel()
+
+	res, err := c.apiKeys().DeleteOne(ctx, bson.D{{Key: "id", Value: id}, {Key: "user_id", Value: userID}})
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// CreateAPIKey saves new API key of user
+func (c *MemoryRepository) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return models.APIKey{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key.ID, key.CreatedAt = uuid.New(), time.Now().UTC()
+	c.apiKeys[key.ID] = key
+	return key, nil
+}
+
+// ListAPIKeys returns API keys of user, oldest first
+func (c *MemoryRepository) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	keys := make([]models.APIKey, 0)
+	for _, key := range c.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// GetAPIKeyByHash returns API key by SHA-256 'hash' of it
+func (c *MemoryRepository) GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return models.APIKey{}, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, key := range c.apiKeys {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+	return models.APIKey{}, ErrAPIKeyNotFound
+}
+
+// DeleteAPIKey deletes API key 'id' of user
+func (c *MemoryRepository) DeleteAPIKey(ctx context.Context, userID, id uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, ok := c.apiKeys[id]
+	if !ok || key.UserID != userID {
+		return ErrAPIKeyNotFound
+	}
+	delete(c.apiKeys, id)
+	return nil
+}
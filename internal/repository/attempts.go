@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// LoginAttempts counts failed logins by keys like username or IP and blocks logins by them for a while
+type LoginAttempts interface {
+	// RegisterFailure increments count of failures by 'key', count is reset after 'window' without failures
+	RegisterFailure(ctx context.Context, key string, window time.Duration) (int64, error)
+	// ResetFailures forgets failures by 'key'
+	ResetFailures(ctx context.Context, key string) error
+	// Block rejects logins by 'key' for 'ttl'
+	Block(ctx context.Context, key string, ttl time.Duration) error
+	// BlockedFor returns time left till logins by 'key' are allowed, it's 0 if they aren't blocked
+	BlockedFor(ctx context.Context, key string) (time.Duration, error)
+}
+
+// RedisLoginAttempts keeps failed logins in redis so that they are shared by all instances
+type RedisLoginAttempts struct {
+	rdb     *redis.Client
+	timeout time.Duration
+}
+
+// NewRedisLoginAttempts is constructor
+func NewRedisLoginAttempts(rdb *redis.Client, timeout time.Duration) *RedisLoginAttempts {
+	return &RedisLoginAttempts{rdb: rdb, timeout: timeout}
+}
+
+func failuresKey(key string) string {
+	return "loginfail:" + key
+}
+
+func blockKey(key string) string {
+	return "loginblock:" + key
+}
+
+// RegisterFailure increments count of failures by 'key' in redis
+func (s *RedisLoginAttempts) RegisterFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var incr *redis.IntCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, failuresKey(key))
+		pipe.PExpire(ctx, failuresKey(key), window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// ResetFailures deletes failures by 'key' from redis
+func (s *RedisLoginAttempts) ResetFailures(ctx context.Context, key string) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.rdb.Del(ctx, failuresKey(key)).Err()
+}
+
+// Block saves block of 'key' in redis
+func (s *RedisLoginAttempts) Block(ctx context.Context, key string, ttl time.Duration) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.rdb.Set(ctx, blockKey(key), 1, ttl).Err()
+}
+
+// BlockedFor reads time left of block of 'key' from redis
+func (s *RedisLoginAttempts) BlockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	ttl, err := s.rdb.PTTL(ctx, blockKey(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+	// negative ttl means that key is missing
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// memoryFailures is a count of failures expiring after a window without failures
+type memoryFailures struct {
+	count   int64
+	expires time.Time
+}
+
+// MemoryLoginAttempts keeps failed logins in memory, it's used with memory backend
+type MemoryLoginAttempts struct {
+	mu       sync.Mutex
+	failures map[string]memoryFailures
+	blocks   map[string]time.Time
+}
+
+// NewMemoryLoginAttempts is constructor
+func NewMemoryLoginAttempts() *MemoryLoginAttempts {
+	return &MemoryLoginAttempts{
+		failures: make(map[string]memoryFailures),
+		blocks:   make(map[string]time.Time),
+	}
+}
+
+// RegisterFailure increments count of failures by 'key'
+func (s *MemoryLoginAttempts) RegisterFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for other, failures := range s.failures {
+		if now.After(failures.expires) {
+			delete(s.failures, other)
+		}
+	}
+	failures := s.failures[key]
+	failures.count++
+	failures.expires = now.Add(window)
+	s.failures[key] = failures
+	return failures.count, nil
+}
+
+// ResetFailures forgets failures by 'key'
+func (s *MemoryLoginAttempts) ResetFailures(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, key)
+	return nil
+}
+
+// Block rejects logins by 'key' for 'ttl'
+func (s *MemoryLoginAttempts) Block(ctx context.Context, key string, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.blocks[key] = time.Now().Add(ttl)
+	return nil
+}
+
+// BlockedFor returns time left till logins by 'key' are allowed
+func (s *MemoryLoginAttempts) BlockedFor(ctx context.Context, key string) (time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	left := time.Until(s.blocks[key])
+	if left <= 0 {
+		delete(s.blocks, key)
+		return 0, nil
+	}
+	return left, nil
+}
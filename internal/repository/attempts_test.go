@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLoginAttempts(t *testing.T) {
+	ctx := context.Background()
+	attempts := NewMemoryLoginAttempts()
+
+	failures, err := attempts.RegisterFailure(ctx, "user:steve", time.Minute)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, failures)
+	failures, err = attempts.RegisterFailure(ctx, "user:steve", time.Millisecond)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, failures)
+	time.Sleep(5 * time.Millisecond)
+	failures, err = attempts.RegisterFailure(ctx, "user:steve", time.Minute)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, failures, "failures expire after window")
+
+	require.NoError(t, attempts.Block(ctx, "user:steve", time.Minute))
+	left, err := attempts.BlockedFor(ctx, "user:steve")
+	require.NoError(t, err)
+	assert.Greater(t, left, 50*time.Second)
+	left, err = attempts.BlockedFor(ctx, "user:other")
+	require.NoError(t, err)
+	assert.Zero(t, left)
+}
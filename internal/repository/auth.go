@@ -1,76 +1,302 @@
 package repository
 
 import (
+	"CatsGo/internal/apperror"
 	"CatsGo/internal/models"
 	"context"
 	"errors"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	log "github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var (
+	// ErrUserNotFound is returned when user with requested username is missing in database
+	ErrUserNotFound = apperror.New(apperror.NotFound, "user doesn't exist in database")
+	// ErrUsernameTaken is returned when another user has the same username in any case
+	ErrUsernameTaken = apperror.New(apperror.Conflict, "username is already taken")
+	// ErrEmailTaken is returned when another user has the same email in any case
+	ErrEmailTaken = apperror.New(apperror.Conflict, "email is already taken")
+)
+
+// pgUniqueViolation is a code of error of postgres on violation of unique index
+const pgUniqueViolation = "23505"
+
 // Auth interface init
 type Auth interface {
-	CreateUser(user models.User) (models.User, error)
-	GetUser(username, password string) (models.User, error)
+	// CreateUser returns ErrUsernameTaken or ErrEmailTaken if username or email of user isn't unique
+	CreateUser(ctx context.Context, user models.User) (models.User, error)
+	// GetUser returns user by 'username' together with hash of password
+	GetUser(ctx context.Context, username string) (models.User, error)
+	// UpdatePassword replaces hash of password of user with 'id'
+	UpdatePassword(ctx context.Context, id uuid.UUID, password string) error
+	// GetUserByID returns user by 'id' without password
+	GetUserByID(ctx context.Context, id uuid.UUID) (models.User, error)
+	// SetUserRole assigns 'role' to user with 'id'
+	SetUserRole(ctx context.Context, id uuid.UUID, role string) (models.User, error)
+	// GetUserByEmail returns user by 'email' in any case without password
+	GetUserByEmail(ctx context.Context, email string) (models.User, error)
+	// SetEmailVerified marks email of user with 'id' as verified
+	SetEmailVerified(ctx context.Context, id uuid.UUID) error
+
+	// CreateAPIKey saves API key of user, only SHA-256 hash of key is stored
+	CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error)
+	// ListAPIKeys returns all API keys of user with 'userID', oldest first
+	ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error)
+	// GetAPIKeyByHash returns ErrAPIKeyNotFound if there is no key with SHA-256 'hash'
+	GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error)
+	// DeleteAPIKey returns ErrAPIKeyNotFound if user has no key with 'id'
+	DeleteAPIKey(ctx context.Context, userID, id uuid.UUID) error
+
+	// SetTOTPSecret saves unconfirmed 'secret' of user, TOTP stays disabled
+	SetTOTPSecret(ctx context.Context, id uuid.UUID, secret string) error
+	// EnableTOTP turns on TOTP of user and replaces hashes of recovery codes
+	EnableTOTP(ctx context.Context, id uuid.UUID, recoveryCodes []string) error
+	// DisableTOTP turns off TOTP of user and removes its secret and recovery codes
+	DisableTOTP(ctx context.Context, id uuid.UUID) error
+	// UseTOTPCounter saves time step of accepted TOTP code, it returns ErrTOTPCodeUsed
+	// if code of the same or later step is already accepted
+	UseTOTPCounter(ctx context.Context, id uuid.UUID, counter int64) error
+	// UseRecoveryCode removes recovery code with SHA-256 'hash', it returns ErrRecoveryCodeNotFound
+	// if user has no such code
+	UseRecoveryCode(ctx context.Context, id uuid.UUID, hash string) error
 }
 
 // CreateUser creates new user in pgdb
-func (c *PostgresRepository) CreateUser(user models.User) (models.User, error) {
+func (c *PostgresRepository) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
+
 	var userData models.User
 
 	id := uuid.New()
-	row := c.conn.QueryRow(context.Background(), "INSERT INTO users (ID, Name, Username, Password) "+
-		"VALUES ($1, $2, $3, $4) RETURNING id, name, username",
-		id, user.Name, user.Username, user.Password)
-	err := row.Scan(&userData.ID, &userData.Name, &userData.Username)
+	row := c.conn.QueryRow(ctx, "INSERT INTO users (ID, Name, Username, Password, Role, Email) "+
+		"VALUES ($1, $2, $3, $4, $5, NULLIF($6, '')) RETURNING id, name, username, role, "+emailColumns,
+		id, user.Name, user.Username, user.Password, user.Role, user.Email)
+	err := row.Scan(&userData.ID, &userData.Name, &userData.Username, &userData.Role, &userData.Email,
+		&userData.EmailVerified)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		if pgErr.ConstraintName == "users_email_key" {
+			return models.User{}, ErrEmailTaken
+		}
+		return models.User{}, ErrUsernameTaken
+	}
 	if err != nil {
 		log.Error(err)
-		return userData, errors.New("error while creating new user in database")
+		return models.User{}, errors.New("error while creating new user in database")
 	}
 
 	return userData, nil
 }
 
 // GetUser get user from pgdb
-func (c *PostgresRepository) GetUser(username, password string) (models.User, error) {
+func (c *PostgresRepository) GetUser(ctx context.Context, username string) (models.User, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
+
 	var user models.User
 
-	err := c.conn.QueryRow(context.Background(), "SELECT id, name, username, password "+
-		"FROM users WHERE username = $1", username).Scan(&user.ID, &user.Name, &user.Username, &user.Password)
+	err := c.conn.QueryRow(ctx, "SELECT id, name, username, password, role, "+emailColumns+", "+mfaColumns+
+		" FROM users WHERE lower(username) = lower($1)", username).Scan(&user.ID, &user.Name, &user.Username, &user.Password, &user.Role,
+		&user.Email, &user.EmailVerified, &user.TOTPSecret, &user.TOTPEnabled, &user.TOTPLastCounter, &user.RecoveryCodes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.User{}, ErrUserNotFound
+	}
+	if err != nil {
+		log.Error(err)
+		return models.User{}, err
+	}
+
+	return user, nil
+}
 
+// UpdatePassword updates hash of password of user in pgdb
+func (c *PostgresRepository) UpdatePassword(ctx context.Context, id uuid.UUID, password string) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
+
+	tag, err := c.conn.Exec(ctx, "UPDATE users SET password = $1 WHERE id = $2", password, id)
 	if err != nil {
 		log.Error(err)
-		return models.User{}, errors.New("user doesn't exist in database")
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
 	}
+	return nil
+}
+
+// GetUserByID get user by 'id' from pgdb
+func (c *PostgresRepository) GetUserByID(ctx context.Context, id uuid.UUID) (models.User, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
 
-	if user.Password != password {
-		return models.User{}, errors.New("incorrect password")
+	var user models.User
+	err := c.conn.QueryRow(ctx, "SELECT id, name, username, role, "+emailColumns+", "+mfaColumns+
+		" FROM users WHERE id = $1", id).Scan(&user.ID, &user.Name, &user.Username, &user.Role, &user.Email,
+		&user.EmailVerified, &user.TOTPSecret, &user.TOTPEnabled, &user.TOTPLastCounter, &user.RecoveryCodes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.User{}, ErrUserNotFound
 	}
+	if err != nil {
+		log.Error(err)
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// SetUserRole updates role of user in pgdb
+func (c *PostgresRepository) SetUserRole(ctx context.Context, id uuid.UUID, role string) (models.User, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
 
+	var user models.User
+	err := c.conn.QueryRow(ctx, "UPDATE users SET role = $1 WHERE id = $2 RETURNING id, name, username, role, "+
+		emailColumns, role, id).Scan(&user.ID, &user.Name, &user.Username, &user.Role, &user.Email, &user.EmailVerified)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.User{}, ErrUserNotFound
+	}
+	if err != nil {
+		log.Error(err)
+		return models.User{}, err
+	}
 	return user, nil
 }
 
+// Names of unique indexes of users in mongodb, they tell which field of user isn't unique
+const (
+	mongoUsernameIndex = "username_unique"
+	mongoEmailIndex    = "email_unique"
+)
+
+// mongoUserCollation compares usernames and emails in any case, indexes and queries of users must use it
+var mongoUserCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// users returns collection of users in mongodb
+func (c *MongoRepository) users() *mongo.Collection {
+	return c.client.Database(c.cfg.MongoDBName).Collection(c.cfg.MongoUsersCollection)
+}
+
 // CreateUser creates new user in mongodb
-func (c *MongoRepository) CreateUser(user models.User) (models.User, error) {
-	collection := c.client.Database("users").Collection("users")
+func (c *MongoRepository) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.MongoTimeout)
+	defer cancel()
 
-	docs := []interface{}{
-		bson.D{primitive.E{Key: "name", Value: user.Name}, {Key: "username", Value: user.Username},
-			{Key: "password", Value: user.Password}},
+	user.ID = uuid.New()
+	user.EmailVerified, user.TOTPSecret, user.TOTPEnabled, user.RecoveryCodes = false, "", false, []string{}
+	_, err := c.users().InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		if strings.Contains(err.Error(), mongoEmailIndex) {
+			return models.User{}, ErrEmailTaken
+		}
+		return models.User{}, ErrUsernameTaken
+	}
+	if err != nil {
+		log.Error(err)
+		return models.User{}, errors.New("error while creating new user in database")
 	}
 
-	_, insertErr := collection.InsertMany(context.TODO(), docs)
-	if insertErr != nil {
-		log.Fatal(insertErr)
+	return publicUser(user), nil
+}
+
+// createUsersIndex creates unique indexes on usernames and emails, they are compared in any case,
+// users without email are skipped since empty email isn't saved
+func (c *MongoRepository) createUsersIndex(ctx context.Context) error {
+	_, err := c.users().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{primitive.E{Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{primitive.E{Key: "username", Value: 1}},
+			Options: options.Index().SetName(mongoUsernameIndex).SetUnique(true).
+				SetCollation(mongoUserCollation),
+		},
+		{
+			Keys: bson.D{primitive.E{Key: "email", Value: 1}},
+			Options: options.Index().SetName(mongoEmailIndex).SetUnique(true).
+				SetCollation(mongoUserCollation).
+				SetPartialFilterExpression(bson.D{primitive.E{Key: "email", Value: bson.D{
+					primitive.E{Key: "$exists", Value: true}}}}),
+		},
+	})
+	return err
+}
+
+// findUser returns user matching 'filter' from mongodb together with hash of password
+func (c *MongoRepository) findUser(ctx context.Context, filter bson.D) (models.User, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.MongoTimeout)
+	defer cancel()
+
+	var user models.User
+	err := c.users().FindOne(ctx, filter, options.FindOne().SetCollation(mongoUserCollation)).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.User{}, ErrUserNotFound
+	}
+	if err != nil {
+		log.Error(err)
+		return models.User{}, err
 	}
+	return user, nil
+}
+
+// updateUser applies 'update' to user with 'id' in mongodb, it returns ErrUserNotFound if nothing is matched
+func (c *MongoRepository) updateUser(ctx context.Context, id uuid.UUID, update bson.D) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.MongoTimeout)
+	defer cancel()
 
-	return models.User{}, nil
+	res, err := c.users().UpdateOne(ctx, bson.D{primitive.E{Key: "id", Value: id}}, update)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
 }
 
 // GetUser get user from mongodb
-func (c *MongoRepository) GetUser(username, password string) (models.User, error) {
-	return models.User{}, nil
+func (c *MongoRepository) GetUser(ctx context.Context, username string) (models.User, error) {
+	return c.findUser(ctx, bson.D{primitive.E{Key: "username", Value: username}})
+}
+
+// UpdatePassword updates hash of password of user in mongodb
+func (c *MongoRepository) UpdatePassword(ctx context.Context, id uuid.UUID, password string) error {
+	return c.updateUser(ctx, id, bson.D{primitive.E{Key: "$set", Value: bson.D{
+		primitive.E{Key: "password", Value: password}}}})
+}
+
+// GetUserByID get user by 'id' from mongodb
+func (c *MongoRepository) GetUserByID(ctx context.Context, id uuid.UUID) (models.User, error) {
+	user, err := c.findUser(ctx, bson.D{primitive.E{Key: "id", Value: id}})
+	user.Password = ""
+	return user, err
+}
+
+// SetUserRole updates role of user in mongodb
+func (c *MongoRepository) SetUserRole(ctx context.Context, id uuid.UUID, role string) (models.User, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.MongoTimeout)
+	defer cancel()
+
+	var user models.User
+	err := c.users().FindOneAndUpdate(ctx, bson.D{primitive.E{Key: "id", Value: id}},
+		bson.D{primitive.E{Key: "$set", Value: bson.D{primitive.E{Key: "role", Value: role}}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.User{}, ErrUserNotFound
+	}
+	if err != nil {
+		log.Error(err)
+		return models.User{}, err
+	}
+	return publicUser(user), nil
 }
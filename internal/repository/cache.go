@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrCacheMiss is returned when key is missing in cache
+	ErrCacheMiss = errors.New("cache miss")
+	// ErrCachedNotFound is returned when cache remembers that value is missing in database
+	ErrCachedNotFound = errors.New("value is cached as missing")
+	// ErrLocked is returned when lock of key is held by someone else
+	ErrLocked = errors.New("cache key is locked")
+)
+
+// notFoundValue marks negative entries, JSON of real values is never equal to it
+const notFoundValue = "null"
+
+// unlockScript deletes lock only if it's still held by the same owner
+var unlockScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0`)
+
+// CacheStats contains counters of cache lookups
+type CacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	// EarlyRefreshes counts hits treated as misses to refresh value before it expires
+	EarlyRefreshes uint64 `json:"early_refreshes"`
+}
+
+// RedisCacheOptions contains settings of RedisCache
+type RedisCacheOptions struct {
+	// Prefix is prepended to all keys of cache
+	Prefix string
+	// Timeout limits every single command to redis
+	Timeout time.Duration
+	// TTL is a lifetime of values
+	TTL time.Duration
+	// NegativeTTL is a lifetime of negative entries
+	NegativeTTL time.Duration
+	// LockTTL is a lifetime of locks, they are released earlier by owners
+	LockTTL time.Duration
+	// EarlyRefreshBeta turns on probabilistic early refresh of values when it's positive,
+	// values are refreshed earlier when it's bigger
+	EarlyRefreshBeta float64
+}
+
+// cacheEntry wraps cached value with data needed for probabilistic early refresh
+type cacheEntry struct {
+	Value json.RawMessage `json:"v"`
+	// Delta is a time spent to compute value
+	Delta time.Duration `json:"d"`
+	// Expiry is a unix time in nanoseconds when value expires
+	Expiry int64 `json:"e"`
+}
+
+// RedisCache keeps values encoded to JSON in redis with expiration
+type RedisCache struct {
+	// counters go first to be 64-bit aligned for atomic operations
+	hits           uint64
+	misses         uint64
+	earlyRefreshes uint64
+	rdb            *redis.Client
+	opts           RedisCacheOptions
+}
+
+// NewRedisCache creates cache of values in redis
+func NewRedisCache(rdb *redis.Client, opts RedisCacheOptions) *RedisCache {
+	return &RedisCache{rdb: rdb, opts: opts}
+}
+
+// Get decodes value of 'key' into 'value', it returns ErrCacheMiss if key is missing
+// and ErrCachedNotFound for negative entries
+func (c *RedisCache) Get(ctx context.Context, key string, value interface{}) error {
+	ctx, cancel := withTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	data, err := c.rdb.Get(ctx, c.opts.Prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		atomic.AddUint64(&c.misses, 1)
+		return ErrCacheMiss
+	}
+	if err != nil {
+		return err
+	}
+	if string(data) == notFoundValue {
+		atomic.AddUint64(&c.hits, 1)
+		return ErrCachedNotFound
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || len(entry.Value) == 0 {
+		// values of unknown format are refreshed
+		atomic.AddUint64(&c.misses, 1)
+		return ErrCacheMiss
+	}
+	if shouldRefreshEarly(time.Now(), entry, c.opts.EarlyRefreshBeta, rand.Float64()) {
+		atomic.AddUint64(&c.earlyRefreshes, 1)
+		atomic.AddUint64(&c.misses, 1)
+		return ErrCacheMiss
+	}
+	atomic.AddUint64(&c.hits, 1)
+	return json.Unmarshal(entry.Value, value)
+}
+
+// shouldRefreshEarly implements probabilistic early expiration (XFetch): the closer expiry
+// and the longer value is computed, the more likely value is refreshed, 'rnd' is in (0, 1]
+func shouldRefreshEarly(now time.Time, entry cacheEntry, beta, rnd float64) bool {
+	if beta <= 0 || entry.Delta <= 0 || rnd <= 0 {
+		return false
+	}
+	gap := -float64(entry.Delta) * beta * math.Log(rnd)
+	return float64(now.UnixNano())+gap >= float64(entry.Expiry)
+}
+
+// Set saves 'value' encoded to JSON, 'delta' is a time spent to compute value
+func (c *RedisCache) Set(ctx context.Context, key string, value interface{}, delta time.Duration) error {
+	ctx, cancel := withTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(cacheEntry{
+		Value:  raw,
+		Delta:  delta,
+		Expiry: time.Now().Add(c.opts.TTL).UnixNano(),
+	})
+	if err != nil {
+		return err
+	}
+	return c.rdb.Set(ctx, c.opts.Prefix+key, data, c.opts.TTL).Err()
+}
+
+// SetNotFound remembers that value of 'key' is missing in database
+func (c *RedisCache) SetNotFound(ctx context.Context, key string) error {
+	ctx, cancel := withTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	return c.rdb.Set(ctx, c.opts.Prefix+key, notFoundValue, c.opts.NegativeTTL).Err()
+}
+
+// Delete invalidates 'key'
+func (c *RedisCache) Delete(ctx context.Context, key string) error {
+	ctx, cancel := withTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	return c.rdb.Del(ctx, c.opts.Prefix+key).Err()
+}
+
+// Lock takes short lock of 'key' shared by all instances of app, it returns ErrLocked
+// if lock is held by someone else, lock expires by itself if owner doesn't release it
+func (c *RedisCache) Lock(ctx context.Context, key string) (unlock func(), err error) {
+	ctx, cancel := withTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	lockKey, token := "lock:"+c.opts.Prefix+key, uuid.NewString()
+	ok, err := c.rdb.SetNX(ctx, lockKey, token, c.opts.LockTTL).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrLocked
+	}
+	return func() {
+		// lock is released even if request is already canceled
+		ctx, cancel := withTimeout(context.Background(), c.opts.Timeout)
+		defer cancel()
+		if err := unlockScript.Run(ctx, c.rdb, []string{lockKey}, token).Err(); err != nil {
+			log.Error(err)
+		}
+	}, nil
+}
+
+// Stats returns counters of lookups since start of app
+func (c *RedisCache) Stats() CacheStats {
+	return CacheStats{
+		Hits:           atomic.LoadUint64(&c.hits),
+		Misses:         atomic.LoadUint64(&c.misses),
+		EarlyRefreshes: atomic.LoadUint64(&c.earlyRefreshes),
+	}
+}
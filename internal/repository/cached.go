@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"CatsGo/internal/configs"
+	"CatsGo/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+// cacheLockPollInterval is how often cache is checked while another instance of app reads cat from database
+const cacheLockPollInterval = 10 * time.Millisecond
+
+// CatsCache contains methods of cache for single cats, it's implemented by RedisRepository
+type CatsCache interface {
+	// GetCat returns ErrCacheMiss if cat isn't cached and ErrCatNotFound if it's cached as missing
+	GetCat(ctx context.Context, id uuid.UUID) (*models.Cats, error)
+	// SetCat caches cat, 'delta' is a time spent to read it from database
+	SetCat(ctx context.Context, cat models.Cats, delta time.Duration) error
+	CatNotFound(ctx context.Context, id uuid.UUID) error
+	DeleteCat(ctx context.Context, id uuid.UUID) error
+	// LockCat returns ErrLocked if cat is being read from database by another instance of app
+	LockCat(ctx context.Context, id uuid.UUID) (unlock func(), err error)
+}
+
+// CachedRepository wraps Repository, caches cats read by 'id' and invalidates them on writes,
+// errors of cache are logged and never fail requests
+type CachedRepository struct {
+	repository Repository
+	cache      CatsCache
+	// group coalesces concurrent misses of the same cat into one read from database
+	group       singleflight.Group
+	lockWait    time.Duration
+	loadTimeout time.Duration
+}
+
+// NewCachedRepository creates caching decorator of 'rps'
+func NewCachedRepository(rps Repository, cache CatsCache, cfg *configs.Config) *CachedRepository {
+	return &CachedRepository{repository: rps, cache: cache, lockWait: cfg.CacheLockTTL, loadTimeout: cfg.CacheLoadTimeout}
+}
+
+// GetAllCats isn't cached
+func (c *CachedRepository) GetAllCats(ctx context.Context, query models.CatsQuery) (*models.CatsPage, error) {
+	return c.repository.GetAllCats(ctx, query)
+}
+
+// CreateCat creates cat in wrapped repository and caches it
+func (c *CachedRepository) CreateCat(ctx context.Context, cats models.Cats) (*models.Cats, error) {
+	cat, err := c.repository.CreateCat(ctx, cats)
+	if err != nil {
+		return cat, err
+	}
+	if err := c.cache.SetCat(ctx, *cat, 0); err != nil {
+		log.Error(err)
+	}
+	return cat, nil
+}
+
+// GetCat returns cached cat, cats missing in cache are read from wrapped repository,
+// concurrent misses of the same cat share one read, which isn't canceled with any of them
+func (c *CachedRepository) GetCat(ctx context.Context, id uuid.UUID) (*models.Cats, error) {
+	cat, err := c.cache.GetCat(ctx, id)
+	if err == nil || errors.Is(err, ErrCatNotFound) {
+		return cat, err
+	}
+
+	ch := c.group.DoChan(id.String(), func() (interface{}, error) {
+		ctx, cancel := withTimeout(context.Background(), c.loadTimeout)
+		defer cancel()
+		return c.loadCat(ctx, id)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		// every caller gets its own copy of shared cat
+		copied := *res.Val.(*models.Cats)
+		return &copied, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// loadCat reads cat from wrapped repository and caches it, if another instance of app
+// is already reading it, loadCat waits for it to appear in cache first
+func (c *CachedRepository) loadCat(ctx context.Context, id uuid.UUID) (*models.Cats, error) {
+	unlock, err := c.cache.LockCat(ctx, id)
+	switch {
+	case err == nil:
+		defer unlock()
+	case errors.Is(err, ErrLocked):
+		cat, err := c.waitCat(ctx, id)
+		if !errors.Is(err, ErrCacheMiss) {
+			return cat, err
+		}
+	default:
+		log.Error(err)
+	}
+
+	start := time.Now()
+	cat, err := c.repository.GetCat(ctx, id)
+	if errors.Is(err, ErrCatNotFound) {
+		if err := c.cache.CatNotFound(ctx, id); err != nil {
+			log.Error(err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := c.cache.SetCat(ctx, *cat, time.Since(start)); err != nil {
+		log.Error(err)
+	}
+	return cat, nil
+}
+
+// waitCat polls cache until cat appears in it, it returns ErrCacheMiss if cat doesn't appear
+// while lock of another instance of app may be held
+func (c *CachedRepository) waitCat(ctx context.Context, id uuid.UUID) (*models.Cats, error) {
+	ticker := time.NewTicker(cacheLockPollInterval)
+	defer ticker.Stop()
+	timeout := time.NewTimer(c.lockWait)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout.C:
+			return nil, ErrCacheMiss
+		case <-ticker.C:
+		}
+		cat, err := c.cache.GetCat(ctx, id)
+		if err == nil || errors.Is(err, ErrCatNotFound) {
+			return cat, err
+		}
+		if !errors.Is(err, ErrCacheMiss) {
+			log.Error(err)
+			return nil, ErrCacheMiss
+		}
+	}
+}
+
+// UpdateCat updates cat in wrapped repository and invalidates it in cache
+func (c *CachedRepository) UpdateCat(ctx context.Context, id uuid.UUID, cats models.Cats) (*models.Cats, error) {
+	cat, err := c.repository.UpdateCat(ctx, id, cats)
+	if err != nil {
+		return cat, err
+	}
+	if err := c.cache.DeleteCat(ctx, id); err != nil {
+		log.Error(err)
+	}
+	return cat, nil
+}
+
+// DeleteCat deletes cat in wrapped repository and invalidates it in cache,
+// missing cat is invalidated too in case it's still cached
+func (c *CachedRepository) DeleteCat(ctx context.Context, id uuid.UUID) error {
+	err := c.repository.DeleteCat(ctx, id)
+	if err != nil && !errors.Is(err, ErrCatNotFound) {
+		return err
+	}
+	if err := c.cache.DeleteCat(ctx, id); err != nil {
+		log.Error(err)
+	}
+	return err
+}
+
+// SearchCats isn't cached
+func (c *CachedRepository) SearchCats(ctx context.Context, query string, limit int) ([]*models.Cats, error) {
+	return c.repository.SearchCats(ctx, query, limit)
+}
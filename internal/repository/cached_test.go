@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"CatsGo/internal/configs"
+	"CatsGo/internal/models"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCatsCache keeps cats in map, nil value marks negative entries
+type fakeCatsCache struct {
+	mu     sync.Mutex
+	cats   map[uuid.UUID]*models.Cats
+	locked map[uuid.UUID]bool
+	err    error
+}
+
+func newFakeCatsCache() *fakeCatsCache {
+	return &fakeCatsCache{cats: make(map[uuid.UUID]*models.Cats), locked: make(map[uuid.UUID]bool)}
+}
+
+func (f *fakeCatsCache) GetCat(_ context.Context, id uuid.UUID) (*models.Cats, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	cat, ok := f.cats[id]
+	switch {
+	case !ok:
+		return nil, ErrCacheMiss
+	case cat == nil:
+		return nil, ErrCatNotFound
+	}
+	copied := *cat
+	return &copied, nil
+}
+
+func (f *fakeCatsCache) SetCat(_ context.Context, cat models.Cats, _ time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cats[cat.ID] = &cat
+	return f.err
+}
+
+func (f *fakeCatsCache) CatNotFound(_ context.Context, id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cats[id] = nil
+	return f.err
+}
+
+func (f *fakeCatsCache) DeleteCat(_ context.Context, id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.cats, id)
+	return f.err
+}
+
+func (f *fakeCatsCache) LockCat(_ context.Context, id uuid.UUID) (func(), error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.locked[id] {
+		return nil, ErrLocked
+	}
+	f.locked[id] = true
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.locked, id)
+	}, nil
+}
+
+// slowRepository counts reads of cats and holds them until 'release' is closed
+type slowRepository struct {
+	Repository
+	reads   int32
+	release chan struct{}
+}
+
+func (s *slowRepository) GetCat(ctx context.Context, id uuid.UUID) (*models.Cats, error) {
+	atomic.AddInt32(&s.reads, 1)
+	<-s.release
+	return s.Repository.GetCat(ctx, id)
+}
+
+var testCacheConfig = &configs.Config{CacheLockTTL: 200 * time.Millisecond}
+
+func TestCachedRepository(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemoryRepository()
+	cache := newFakeCatsCache()
+	rps := NewCachedRepository(memory, cache, testCacheConfig)
+
+	cat, err := rps.CreateCat(ctx, models.Cats{Name: "Barsik"})
+	require.NoError(t, err)
+	assert.Contains(t, cache.cats, cat.ID, "created cat is cached")
+
+	// cat is served by cache even if it's changed behind it
+	_, err = memory.UpdateCat(ctx, cat.ID, models.Cats{Name: "Pushok"})
+	require.NoError(t, err)
+	cached, err := rps.GetCat(ctx, cat.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Barsik", cached.Name)
+
+	// update invalidates cache
+	_, err = rps.UpdateCat(ctx, cat.ID, models.Cats{Name: "Snejok"})
+	require.NoError(t, err)
+	assert.NotContains(t, cache.cats, cat.ID)
+	cached, err = rps.GetCat(ctx, cat.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Snejok", cached.Name)
+	assert.Contains(t, cache.cats, cat.ID, "cat read from database is cached")
+
+	// delete invalidates cache and missing cat is cached as negative entry
+	require.NoError(t, rps.DeleteCat(ctx, cat.ID))
+	_, err = rps.GetCat(ctx, cat.ID)
+	assert.ErrorIs(t, err, ErrCatNotFound)
+	assert.Nil(t, cache.cats[cat.ID])
+	assert.Contains(t, cache.cats, cat.ID)
+
+	// cat deleted behind cache is invalidated on delete too
+	cat, err = rps.CreateCat(ctx, models.Cats{Name: "Barsik"})
+	require.NoError(t, err)
+	require.NoError(t, memory.DeleteCat(ctx, cat.ID))
+	assert.ErrorIs(t, rps.DeleteCat(ctx, cat.ID), ErrCatNotFound)
+	assert.NotContains(t, cache.cats, cat.ID)
+}
+
+func TestCachedRepository_CacheFailure(t *testing.T) {
+	ctx := context.Background()
+	cache := newFakeCatsCache()
+	cache.err = errors.New("connection refused")
+	rps := NewCachedRepository(NewMemoryRepository(), cache, testCacheConfig)
+
+	cat, err := rps.CreateCat(ctx, models.Cats{Name: "Barsik"})
+	require.NoError(t, err)
+	got, err := rps.GetCat(ctx, cat.ID)
+	require.NoError(t, err)
+	assert.Equal(t, cat, got)
+	require.NoError(t, rps.DeleteCat(ctx, cat.ID))
+}
+
+func TestCachedRepository_Coalescing(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemoryRepository()
+	cat, err := memory.CreateCat(ctx, models.Cats{Name: "Barsik"})
+	require.NoError(t, err)
+
+	slow := &slowRepository{Repository: memory, release: make(chan struct{})}
+	rps := NewCachedRepository(slow, newFakeCatsCache(), testCacheConfig)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([]*models.Cats, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got, err := rps.GetCat(ctx, cat.ID)
+			assert.NoError(t, err)
+			results[i] = got
+		}(i)
+	}
+	// all callers have to miss cache before database answers
+	time.Sleep(50 * time.Millisecond)
+	close(slow.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&slow.reads), "concurrent misses share one read")
+	for _, got := range results {
+		assert.Equal(t, cat, got)
+	}
+	assert.NotSame(t, results[0], results[1], "callers get their own copies")
+}
+
+func TestCachedRepository_CanceledCaller(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemoryRepository()
+	cat, err := memory.CreateCat(ctx, models.Cats{Name: "Barsik"})
+	require.NoError(t, err)
+
+	cache := newFakeCatsCache()
+	slow := &slowRepository{Repository: memory, release: make(chan struct{})}
+	rps := NewCachedRepository(slow, cache, testCacheConfig)
+
+	canceled, cancel := context.WithCancel(ctx)
+	first := make(chan error, 1)
+	go func() {
+		_, err := rps.GetCat(canceled, cat.ID)
+		first <- err
+	}()
+	waiter := make(chan *models.Cats, 1)
+	go func() {
+		got, err := rps.GetCat(ctx, cat.ID)
+		assert.NoError(t, err)
+		waiter <- got
+	}()
+	time.Sleep(30 * time.Millisecond)
+
+	// caller who started shared read gives up, the read goes on for others
+	cancel()
+	assert.ErrorIs(t, <-first, context.Canceled)
+	close(slow.release)
+	assert.Equal(t, cat, <-waiter)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&slow.reads))
+	assert.Contains(t, cache.cats, cat.ID, "cat read for canceled caller is cached")
+}
+
+func TestCachedRepository_Locked(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemoryRepository()
+	cat, err := memory.CreateCat(ctx, models.Cats{Name: "Barsik"})
+	require.NoError(t, err)
+
+	cache := newFakeCatsCache()
+	slow := &slowRepository{Repository: memory, release: make(chan struct{})}
+	close(slow.release)
+	rps := NewCachedRepository(slow, cache, testCacheConfig)
+
+	// another instance of app reads cat from database and caches it
+	unlock, err := cache.LockCat(ctx, cat.ID)
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		assert.NoError(t, cache.SetCat(ctx, *cat, 0))
+		unlock()
+	}()
+	got, err := rps.GetCat(ctx, cat.ID)
+	require.NoError(t, err)
+	assert.Equal(t, cat, got)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&slow.reads), "cat cached by lock owner is used")
+
+	// database is read when lock owner doesn't cache cat in time
+	other, err := memory.CreateCat(ctx, models.Cats{Name: "Pushok"})
+	require.NoError(t, err)
+	_, err = cache.LockCat(ctx, other.ID)
+	require.NoError(t, err)
+	got, err = rps.GetCat(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, other, got)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&slow.reads))
+}
+
+func TestShouldRefreshEarly(t *testing.T) {
+	now := time.Now()
+	entry := cacheEntry{Delta: 100 * time.Millisecond, Expiry: now.Add(500 * time.Millisecond).UnixNano()}
+
+	type TestCase struct {
+		name  string
+		entry cacheEntry
+		beta  float64
+		rnd   float64
+		want  bool
+	}
+	TestTable := []TestCase{
+		{name: "disabled", entry: entry, beta: 0, rnd: 0.0001, want: false},
+		{name: "unknown delta", entry: cacheEntry{Expiry: entry.Expiry}, beta: 1, rnd: 0.0001, want: false},
+		{name: "far from expiry", entry: entry, beta: 1, rnd: 0.5, want: false},
+		{name: "unlucky near expiry", entry: entry, beta: 1, rnd: 0.0001, want: true},
+		{name: "bigger beta", entry: entry, beta: 20, rnd: 0.5, want: true},
+		{name: "expired", entry: cacheEntry{Delta: entry.Delta, Expiry: now.UnixNano()}, beta: 1, rnd: 1, want: true},
+	}
+	for _, testCase := range TestTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.want, shouldRefreshEarly(now, testCase.entry, testCase.beta, testCase.rnd))
+		})
+	}
+}
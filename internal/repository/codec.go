@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+var tUUID = reflect.TypeOf(uuid.UUID{})
+
+// mongoRegistry stores uuid.UUID as BSON binary of UUID subtype instead of array of bytes,
+// so ids are compared and sorted bytewise like in postgres
+var mongoRegistry = bson.NewRegistryBuilder().
+	RegisterTypeEncoder(tUUID, bsoncodec.ValueEncoderFunc(uuidEncodeValue)).
+	RegisterTypeDecoder(tUUID, bsoncodec.ValueDecoderFunc(uuidDecodeValue)).
+	Build()
+
+func uuidEncodeValue(_ bsoncodec.EncodeContext, vw bsonrw.ValueWriter, val reflect.Value) error {
+	if !val.IsValid() || val.Type() != tUUID {
+		return bsoncodec.ValueEncoderError{Name: "uuidEncodeValue", Types: []reflect.Type{tUUID}, Received: val}
+	}
+	id := val.Interface().(uuid.UUID)
+	return vw.WriteBinaryWithSubtype(id[:], bsontype.BinaryUUID)
+}
+
+func uuidDecodeValue(_ bsoncodec.DecodeContext, vr bsonrw.ValueReader, val reflect.Value) error {
+	if !val.CanSet() || val.Type() != tUUID {
+		return bsoncodec.ValueDecoderError{Name: "uuidDecodeValue", Types: []reflect.Type{tUUID}, Received: val}
+	}
+	if vr.Type() == bsontype.Null {
+		val.Set(reflect.Zero(tUUID))
+		return vr.ReadNull()
+	}
+	data, subtype, err := vr.ReadBinary()
+	if err != nil {
+		return err
+	}
+	// earlier versions stored ids as generic binary, they are still readable
+	if subtype != bsontype.BinaryUUID && (subtype != bsontype.BinaryGeneric || len(data) != len(uuid.UUID{})) {
+		return fmt.Errorf("unsupported binary subtype %v for uuid", subtype)
+	}
+	id, err := uuid.FromBytes(data)
+	if err != nil {
+		return err
+	}
+	val.Set(reflect.ValueOf(id))
+	return nil
+}
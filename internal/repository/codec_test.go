@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"CatsGo/internal/models"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMongoRegistry_UUID(t *testing.T) {
+	cat := models.Cats{ID: uuid.New(), Name: "Barsik"}
+
+	data, err := bson.MarshalWithRegistry(mongoRegistry, cat)
+	require.NoError(t, err)
+	subtype, _ := bson.Raw(data).Lookup("id").Binary()
+	assert.Equal(t, bsontype.BinaryUUID, subtype)
+
+	var decoded models.Cats
+	require.NoError(t, bson.UnmarshalWithRegistry(mongoRegistry, data, &decoded))
+	assert.Equal(t, cat.ID, decoded.ID)
+	assert.Equal(t, cat.Name, decoded.Name)
+}
+
+func TestMongoRegistry_UUIDSubtype(t *testing.T) {
+	id := uuid.New()
+	TestTable := []struct {
+		name        string
+		inputData   []byte
+		inputType   byte
+		exceptError bool
+	}{
+		{
+			name:      "uuid subtype",
+			inputData: id[:],
+			inputType: bsontype.BinaryUUID,
+		},
+		{
+			name:      "generic subtype of earlier versions",
+			inputData: id[:],
+			inputType: bsontype.BinaryGeneric,
+		},
+		{
+			name:        "generic subtype with wrong length",
+			inputData:   id[:8],
+			inputType:   bsontype.BinaryGeneric,
+			exceptError: true,
+		},
+		{
+			name:        "user defined subtype",
+			inputData:   id[:],
+			inputType:   bsontype.BinaryUserDefined,
+			exceptError: true,
+		},
+	}
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			data, err := bson.Marshal(bson.M{"id": primitive.Binary{Subtype: TestCase.inputType, Data: TestCase.inputData}})
+			require.NoError(t, err)
+
+			var decoded models.Cats
+			err = bson.UnmarshalWithRegistry(mongoRegistry, data, &decoded)
+			if TestCase.exceptError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, id, decoded.ID)
+		})
+	}
+}
+
+func TestMongoRegistry_User(t *testing.T) {
+	TestTable := []struct {
+		name       string
+		inputUser  models.User
+		exceptMail bool
+	}{
+		{
+			name:       "with email",
+			inputUser:  models.User{ID: uuid.New(), Username: "steve", Password: "hash", Email: "steve@example.com"},
+			exceptMail: true,
+		},
+		{
+			name:      "without email",
+			inputUser: models.User{ID: uuid.New(), Username: "steve", Password: "hash"},
+		},
+	}
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			data, err := bson.MarshalWithRegistry(mongoRegistry, TestCase.inputUser)
+			require.NoError(t, err)
+			// users without email must be skipped by unique index on emails
+			_, err = bson.Raw(data).LookupErr("email")
+			assert.Equal(t, TestCase.exceptMail, err == nil)
+
+			var decoded models.User
+			require.NoError(t, bson.UnmarshalWithRegistry(mongoRegistry, data, &decoded))
+			assert.Equal(t, TestCase.inputUser, decoded)
+		})
+	}
+}
+
+func TestMongoRegistry_APIKey(t *testing.T) {
+	key := models.APIKey{ID: uuid.New(), UserID: uuid.New(), Hash: "hash", Scopes: []string{models.ScopeCatsRead}}
+
+	data, err := bson.MarshalWithRegistry(mongoRegistry, key)
+	require.NoError(t, err)
+	subtype, _ := bson.Raw(data).Lookup("user_id").Binary()
+	assert.Equal(t, bsontype.BinaryUUID, subtype)
+	_, err = bson.Raw(data).LookupErr("expires_at")
+	assert.Error(t, err)
+
+	var decoded models.APIKey
+	require.NoError(t, bson.UnmarshalWithRegistry(mongoRegistry, data, &decoded))
+	assert.Equal(t, key.UserID, decoded.UserID)
+	assert.Equal(t, key.Scopes, decoded.Scopes)
+	assert.Nil(t, decoded.ExpiresAt)
+}
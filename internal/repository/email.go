@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"CatsGo/internal/models"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// emailColumns lists columns of users table related to email, users registered before emails have none
+const emailColumns = "COALESCE(email, ''), email_verified"
+
+// GetUserByEmail get user by 'email' from pgdb
+func (c *PostgresRepository) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
+
+	var user models.User
+	err := c.conn.QueryRow(ctx, "SELECT id, name, username, role, "+emailColumns+
+		" FROM users WHERE lower(email) = lower($1)", email).
+		Scan(&user.ID, &user.Name, &user.Username, &user.Role, &user.Email, &user.EmailVerified)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.User{}, ErrUserNotFound
+	}
+	if err != nil {
+		log.Error(err)
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// SetEmailVerified marks email of user as verified in pgdb
+func (c *PostgresRepository) SetEmailVerified(ctx context.Context, id uuid.UUID) error {
+	return c.updateUser(ctx, "UPDATE users SET email_verified = true WHERE id = $1", id)
+}
+
+// GetUserByEmail get user by 'email' from mongodb
+func (c *MongoRepository) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	if email == "" {
+		return models.User{}, ErrUserNotFound
+	}
+	user, err := c.findUser(ctx, bson.D{primitive.E{Key: "email", Value: email}})
+	if err != nil {
+		return models.User{}, err
+	}
+	return publicUser(user), nil
+}
+
+// SetEmailVerified marks email of user as verified in mongodb
+func (c *MongoRepository) SetEmailVerified(ctx context.Context, id uuid.UUID) error {
+	return c.updateUser(ctx, id, bson.D{primitive.E{Key: "$set", Value: bson.D{
+		primitive.E{Key: "email_verified", Value: true}}}})
+}
+
+// GetUserByEmail returns user by 'email' in any case
+func (c *MemoryRepository) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return models.User{}, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, user := range c.users {
+		if user.Email != "" && strings.EqualFold(user.Email, email) {
+			return publicUser(user), nil
+		}
+	}
+	return models.User{}, ErrUserNotFound
+}
+
+// SetEmailVerified marks email of user as verified
+func (c *MemoryRepository) SetEmailVerified(ctx context.Context, id uuid.UUID) error {
+	return c.updateUser(ctx, id, func(user *models.User) error {
+		user.EmailVerified = true
+		return nil
+	})
+}
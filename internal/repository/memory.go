@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"CatsGo/internal/models"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryRepository keeps cats and users in process memory, it is safe for concurrent use
+type MemoryRepository struct {
+	mu      sync.RWMutex
+	cats    map[uuid.UUID]models.Cats
+	catsIDs []uuid.UUID            // keeps insertion order of cats
+	users   map[string]models.User // keyed by lowercase username
+	apiKeys map[uuid.UUID]models.APIKey
+}
+
+// NewMemoryRepository creates new empty in-memory repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		cats:    make(map[uuid.UUID]models.Cats),
+		users:   make(map[string]models.User),
+		apiKeys: make(map[uuid.UUID]models.APIKey),
+	}
+}
+
+// GetAllCats returns a page of cats
+func (c *MemoryRepository) GetAllCats(ctx context.Context, query models.CatsQuery) (*models.CatsPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	field, desc, cur, err := prepareCatsQuery(&query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	allcats := make([]*models.Cats, 0, len(c.catsIDs))
+	for _, id := range c.catsIDs {
+		cat := c.cats[id]
+		if matchCatsQuery(&cat, &query) {
+			allcats = append(allcats, &cat)
+		}
+	}
+	c.mu.RUnlock()
+
+	total := int64(len(allcats))
+	sort.Slice(allcats, func(i, j int) bool {
+		if desc {
+			return compareCats(allcats[i], allcats[j], field) > 0
+		}
+		return compareCats(allcats[i], allcats[j], field) < 0
+	})
+	if cur != nil {
+		last := &models.Cats{ID: cur.ID, Name: cur.Name, CreatedAt: cur.CreatedAt}
+		allcats = allcats[sort.Search(len(allcats), func(i int) bool {
+			if desc {
+				return compareCats(allcats[i], last, field) < 0
+			}
+			return compareCats(allcats[i], last, field) > 0
+		}):]
+	} else if query.Offset > 0 {
+		if query.Offset > len(allcats) {
+			query.Offset = len(allcats)
+		}
+		allcats = allcats[query.Offset:]
+	}
+	if query.Limit > 0 && len(allcats) > query.Limit+1 {
+		allcats = allcats[:query.Limit+1]
+	}
+	return newCatsPage(allcats, total, &query), nil
+}
+
+// CreateCat saves new cat with generated 'id'
+func (c *MemoryRepository) CreateCat(ctx context.Context, cat models.Cats) (*models.Cats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cat.ID = uuid.New()
+	cat.CreatedAt = time.Now().UTC()
+	cat.UpdatedAt = cat.CreatedAt
+	c.cats[cat.ID] = cat
+	c.catsIDs = append(c.catsIDs, cat.ID)
+	return &cat, nil
+}
+
+// GetCat returns cat by 'id'
+func (c *MemoryRepository) GetCat(ctx context.Context, id uuid.UUID) (*models.Cats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cat, ok := c.cats[id]
+	if !ok {
+		return nil, ErrCatNotFound
+	}
+	return &cat, nil
+}
+
+// UpdateCat updates all fields of cat by 'id' except of creation time
+func (c *MemoryRepository) UpdateCat(ctx context.Context, id uuid.UUID, cats models.Cats) (*models.Cats, error) {
+	if err := ctx.Err(); err != nil {
+		return &cats, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cat, ok := c.cats[id]
+	if !ok {
+		return &cats, ErrCatNotFound
+	}
+	cats.ID, cats.OwnerID, cats.CreatedAt, cats.UpdatedAt = id, cat.OwnerID, cat.CreatedAt, time.Now().UTC()
+	c.cats[id] = cats
+	return &cats, nil
+}
+
+// DeleteCat deletes cat by 'id', it returns ErrCatNotFound if cat is missing
+func (c *MemoryRepository) DeleteCat(ctx context.Context, id uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.cats[id]; !ok {
+		return ErrCatNotFound
+	}
+	delete(c.cats, id)
+	for i, catID := range c.catsIDs {
+		if catID == id {
+			c.catsIDs = append(c.catsIDs[:i], c.catsIDs[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// SearchCats returns at most 'limit' cats with names matching query, best matches first
+func (c *MemoryRepository) SearchCats(ctx context.Context, query string, limit int) ([]*models.Cats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	allcats := make([]*models.Cats, 0, len(c.cats))
+	for _, cat := range c.cats {
+		cat := cat
+		allcats = append(allcats, &cat)
+	}
+	c.mu.RUnlock()
+
+	return rankCats(allcats, query, limit), nil
+}
+
+// CreateUser saves new user with generated 'id', usernames and emails are unique in any case
+func (c *MemoryRepository) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return models.User{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.users[strings.ToLower(user.Username)]; ok {
+		return models.User{}, ErrUsernameTaken
+	}
+	for _, other := range c.users {
+		if user.Email != "" && strings.EqualFold(other.Email, user.Email) {
+			return models.User{}, ErrEmailTaken
+		}
+	}
+	user.ID = uuid.New()
+	c.users[strings.ToLower(user.Username)] = user
+	return publicUser(user), nil
+}
+
+// GetUser returns user by 'username'
+func (c *MemoryRepository) GetUser(ctx context.Context, username string) (models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return models.User{}, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	user, ok := c.users[strings.ToLower(username)]
+	if !ok {
+		return models.User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// UpdatePassword replaces password of user with 'id'
+func (c *MemoryRepository) UpdatePassword(ctx context.Context, id uuid.UUID, password string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for username, user := range c.users {
+		if user.ID == id {
+			user.Password = password
+			c.users[username] = user
+			return nil
+		}
+	}
+	return ErrUserNotFound
+}
+
+// SetUserRole assigns 'role' to user with 'id'
+func (c *MemoryRepository) SetUserRole(ctx context.Context, id uuid.UUID, role string) (models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return models.User{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for username, user := range c.users {
+		if user.ID == id {
+			user.Role = role
+			c.users[username] = user
+			return publicUser(user), nil
+		}
+	}
+	return models.User{}, ErrUserNotFound
+}
+
+// GetUserByID returns user by 'id' without password
+func (c *MemoryRepository) GetUserByID(ctx context.Context, id uuid.UUID) (models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return models.User{}, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, user := range c.users {
+		if user.ID == id {
+			user.Password = ""
+			return user, nil
+		}
+	}
+	return models.User{}, ErrUserNotFound
+}
+
+// publicUser returns user without password and secrets of two-factor authentication
+func publicUser(user models.User) models.User {
+	return models.User{
+		ID: user.ID, Name: user.Name, Username: user.Username, Role: user.Role,
+		Email: user.Email, EmailVerified: user.EmailVerified,
+	}
+}
@@ -0,0 +1,340 @@
+package repository
+
+import (
+	"CatsGo/internal/models"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRepository_Cats(t *testing.T) {
+	rps := NewMemoryRepository()
+	ctx := context.Background()
+
+	barsik, err := rps.CreateCat(ctx, models.Cats{Name: "Barsik"})
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, barsik.ID)
+	snejok, err := rps.CreateCat(ctx, models.Cats{Name: "Snejok"})
+	require.NoError(t, err)
+
+	page, err := rps.GetAllCats(ctx, models.CatsQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []*models.Cats{barsik, snejok}, page.Cats)
+
+	cat, err := rps.GetCat(ctx, barsik.ID)
+	require.NoError(t, err)
+	assert.Equal(t, barsik, cat)
+
+	updated, err := rps.UpdateCat(ctx, barsik.ID, models.Cats{Name: "Pushok", Breed: "Siberian", Weight: 4.5})
+	require.NoError(t, err)
+	cat, err = rps.GetCat(ctx, barsik.ID)
+	require.NoError(t, err)
+	assert.Equal(t, updated, cat)
+	assert.Equal(t, "Pushok", cat.Name)
+	assert.Equal(t, "Siberian", cat.Breed)
+	assert.Equal(t, barsik.CreatedAt, cat.CreatedAt)
+	assert.False(t, cat.UpdatedAt.Before(barsik.UpdatedAt))
+
+	require.NoError(t, rps.DeleteCat(ctx, barsik.ID))
+	_, err = rps.GetCat(ctx, barsik.ID)
+	assert.ErrorIs(t, err, ErrCatNotFound)
+	_, err = rps.UpdateCat(ctx, barsik.ID, models.Cats{Name: "Pushok"})
+	assert.ErrorIs(t, err, ErrCatNotFound)
+	assert.ErrorIs(t, rps.DeleteCat(ctx, barsik.ID), ErrCatNotFound)
+
+	page, err = rps.GetAllCats(ctx, models.CatsQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []*models.Cats{snejok}, page.Cats)
+}
+
+func TestMemoryRepository_GetAllCats(t *testing.T) {
+	rps := NewMemoryRepository()
+	ctx := context.Background()
+	for _, name := range []string{"Barsik", "Snejok", "Murzik", "Barsyk", "Pushok"} {
+		_, err := rps.CreateCat(ctx, models.Cats{Name: name})
+		require.NoError(t, err)
+	}
+
+	names := func(cats []*models.Cats) []string {
+		result := make([]string, 0, len(cats))
+		for _, cat := range cats {
+			result = append(result, cat.Name)
+		}
+		return result
+	}
+
+	TestTable := []struct {
+		name        string
+		query       models.CatsQuery
+		expectNames []string
+		expectTotal int64
+	}{
+		{
+			name:        "sorted by name",
+			query:       models.CatsQuery{Sort: models.SortByName},
+			expectNames: []string{"Barsik", "Barsyk", "Murzik", "Pushok", "Snejok"},
+			expectTotal: 5,
+		},
+		{
+			name:        "sorted by name descending with offset",
+			query:       models.CatsQuery{Sort: models.SortByNameDesc, Offset: 1, Limit: 2},
+			expectNames: []string{"Pushok", "Murzik"},
+			expectTotal: 5,
+		},
+		{
+			name:        "name prefix",
+			query:       models.CatsQuery{Sort: models.SortByName, NamePrefix: "bar"},
+			expectNames: []string{"Barsik", "Barsyk"},
+			expectTotal: 2,
+		},
+		{
+			name:        "name substring",
+			query:       models.CatsQuery{Sort: models.SortByName, NameContains: "ZI"},
+			expectNames: []string{"Murzik"},
+			expectTotal: 1,
+		},
+		{
+			name:        "offset out of range",
+			query:       models.CatsQuery{Offset: 10},
+			expectNames: []string{},
+			expectTotal: 5,
+		},
+	}
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			page, err := rps.GetAllCats(ctx, TestCase.query)
+			require.NoError(t, err)
+
+			assert.Equal(t, TestCase.expectNames, names(page.Cats))
+			assert.Equal(t, TestCase.expectTotal, page.Total)
+		})
+	}
+
+	t.Run("cursor", func(t *testing.T) {
+		var visited []string
+		query := models.CatsQuery{Sort: models.SortByName, Limit: 2}
+		for {
+			page, err := rps.GetAllCats(ctx, query)
+			require.NoError(t, err)
+			visited = append(visited, names(page.Cats)...)
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+		assert.Equal(t, []string{"Barsik", "Barsyk", "Murzik", "Pushok", "Snejok"}, visited)
+	})
+
+	t.Run("cursor of another sort order", func(t *testing.T) {
+		page, err := rps.GetAllCats(ctx, models.CatsQuery{Sort: models.SortByName, Limit: 2})
+		require.NoError(t, err)
+
+		_, err = rps.GetAllCats(ctx, models.CatsQuery{Sort: models.SortByNameDesc, Cursor: page.NextCursor})
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+		_, err = rps.GetAllCats(ctx, models.CatsQuery{Cursor: "garbage"})
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+}
+
+func TestMemoryRepository_SearchCats(t *testing.T) {
+	rps := NewMemoryRepository()
+	ctx := context.Background()
+	for _, name := range []string{"Barsik", "Barsyk Junior", "Snejok", "Murzik"} {
+		_, err := rps.CreateCat(ctx, models.Cats{Name: name})
+		require.NoError(t, err)
+	}
+
+	TestTable := []struct {
+		name        string
+		query       string
+		limit       int
+		expectNames []string
+	}{
+		{
+			name:        "exact word goes first",
+			query:       "barsyk",
+			limit:       10,
+			expectNames: []string{"Barsyk Junior", "Barsik"},
+		},
+		{
+			name:        "typo",
+			query:       "Snejek",
+			limit:       10,
+			expectNames: []string{"Snejok"},
+		},
+		{
+			name:        "limit",
+			query:       "barsik",
+			limit:       1,
+			expectNames: []string{"Barsik"},
+		},
+		{
+			name:        "nothing similar",
+			query:       "Tom",
+			limit:       10,
+			expectNames: []string{},
+		},
+	}
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			allcats, err := rps.SearchCats(ctx, TestCase.query, TestCase.limit)
+			require.NoError(t, err)
+
+			names := make([]string, 0, len(allcats))
+			for _, cat := range allcats {
+				names = append(names, cat.Name)
+			}
+			assert.Equal(t, TestCase.expectNames, names)
+		})
+	}
+}
+
+func TestMemoryRepository_GetUser(t *testing.T) {
+	rps := NewMemoryRepository()
+	ctx := context.Background()
+	created, err := rps.CreateUser(ctx, models.User{Name: "Steve Jobs", Username: "steve", Password: "hash"})
+	require.NoError(t, err)
+	assert.Empty(t, created.Password)
+
+	TestTable := []struct {
+		name          string
+		inputUsername string
+		expectID      uuid.UUID
+		exceptError   error
+	}{
+		{
+			name:          "OK",
+			inputUsername: "steve",
+			expectID:      created.ID,
+		},
+		{
+			name:          "username in other case",
+			inputUsername: "Steve",
+			expectID:      created.ID,
+		},
+		{
+			name:          "user not in database",
+			inputUsername: "carl",
+			exceptError:   ErrUserNotFound,
+		},
+	}
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			user, err := rps.GetUser(ctx, TestCase.inputUsername)
+
+			assert.Equal(t, TestCase.expectID, user.ID)
+			assert.ErrorIs(t, err, TestCase.exceptError)
+		})
+	}
+}
+
+func TestMemoryRepository_CreateUser_Conflict(t *testing.T) {
+	rps := NewMemoryRepository()
+	ctx := context.Background()
+	_, err := rps.CreateUser(ctx, models.User{Name: "Steve Jobs", Username: "steve", Email: "steve@example.com"})
+	require.NoError(t, err)
+
+	TestTable := []struct {
+		name        string
+		inputUser   models.User
+		exceptError error
+	}{
+		{
+			name:        "same username",
+			inputUser:   models.User{Name: "Steve Wozniak", Username: "steve"},
+			exceptError: ErrUsernameTaken,
+		},
+		{
+			name:        "username in other case",
+			inputUser:   models.User{Name: "Steve Wozniak", Username: "STEVE"},
+			exceptError: ErrUsernameTaken,
+		},
+		{
+			name:        "email in other case",
+			inputUser:   models.User{Name: "Steve Wozniak", Username: "woz", Email: "Steve@Example.com"},
+			exceptError: ErrEmailTaken,
+		},
+		{
+			name:      "other username without email",
+			inputUser: models.User{Name: "Steve Wozniak", Username: "woz"},
+		},
+	}
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			_, err := rps.CreateUser(ctx, TestCase.inputUser)
+			assert.ErrorIs(t, err, TestCase.exceptError)
+		})
+	}
+}
+
+func TestMemoryRepository_UpdatePassword(t *testing.T) {
+	rps := NewMemoryRepository()
+	ctx := context.Background()
+	created, err := rps.CreateUser(ctx, models.User{Name: "Steve Jobs", Username: "steve", Password: "hash"})
+	require.NoError(t, err)
+
+	require.NoError(t, rps.UpdatePassword(ctx, created.ID, "new hash"))
+	user, err := rps.GetUser(ctx, "steve")
+	require.NoError(t, err)
+	assert.Equal(t, "new hash", user.Password)
+
+	assert.ErrorIs(t, rps.UpdatePassword(ctx, uuid.New(), "hash"), ErrUserNotFound)
+}
+
+func TestMemoryRepository_Concurrent(t *testing.T) {
+	rps := NewMemoryRepository()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cat, err := rps.CreateCat(ctx, models.Cats{Name: "Barsik"})
+			assert.NoError(t, err)
+			_, err = rps.GetAllCats(ctx, models.CatsQuery{})
+			assert.NoError(t, err)
+			assert.NoError(t, rps.DeleteCat(ctx, cat.ID))
+		}()
+	}
+	wg.Wait()
+
+	page, err := rps.GetAllCats(ctx, models.CatsQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Cats)
+}
+
+func TestMemoryRepository_CanceledContext(t *testing.T) {
+	rps := NewMemoryRepository()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := rps.CreateCat(ctx, models.Cats{Name: "Barsik"})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = rps.GetAllCats(ctx, models.CatsQuery{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestMemoryRepository_UseTOTPCounter(t *testing.T) {
+	ctx := context.Background()
+	rps := NewMemoryRepository()
+	user, err := rps.CreateUser(ctx, models.User{Name: "Steve Jobs", Username: "steve", Password: "hash"})
+	require.NoError(t, err)
+
+	require.NoError(t, rps.UseTOTPCounter(ctx, user.ID, 100))
+	assert.ErrorIs(t, rps.UseTOTPCounter(ctx, user.ID, 100), ErrTOTPCodeUsed)
+	assert.ErrorIs(t, rps.UseTOTPCounter(ctx, user.ID, 99), ErrTOTPCodeUsed)
+	require.NoError(t, rps.UseTOTPCounter(ctx, user.ID, 101))
+	got, err := rps.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(101), got.TOTPLastCounter)
+	assert.ErrorIs(t, rps.UseTOTPCounter(ctx, uuid.New(), 1), ErrUserNotFound)
+}
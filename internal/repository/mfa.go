@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"CatsGo/internal/apperror"
+	"CatsGo/internal/models"
+	"context"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrRecoveryCodeNotFound is returned when user has no unused recovery code
+	ErrRecoveryCodeNotFound = apperror.New(apperror.NotFound, "recovery code doesn't exist in database")
+	// ErrTOTPCodeUsed is returned when TOTP code of the same or later time step is already accepted
+	ErrTOTPCodeUsed = apperror.New(apperror.Conflict, "totp code is already used")
+)
+
+// mfaColumns lists columns of users table related to two-factor authentication
+const mfaColumns = "totp_secret, totp_enabled, totp_last_counter, recovery_codes"
+
+// SetTOTPSecret saves unconfirmed TOTP secret of user in pgdb
+func (c *PostgresRepository) SetTOTPSecret(ctx context.Context, id uuid.UUID, secret string) error {
+	return c.updateUser(ctx, "UPDATE users SET totp_secret = $1, totp_enabled = false, recovery_codes = '{}' "+
+		"WHERE id = $2", secret, id)
+}
+
+// EnableTOTP turns on TOTP of user in pgdb
+func (c *PostgresRepository) EnableTOTP(ctx context.Context, id uuid.UUID, recoveryCodes []string) error {
+	return c.updateUser(ctx, "UPDATE users SET totp_enabled = true, recovery_codes = $1 WHERE id = $2",
+		recoveryCodes, id)
+}
+
+// DisableTOTP turns off TOTP of user in pgdb
+func (c *PostgresRepository) DisableTOTP(ctx context.Context, id uuid.UUID) error {
+	return c.updateUser(ctx, "UPDATE users SET totp_secret = '', totp_enabled = false, recovery_codes = '{}' "+
+		"WHERE id = $1", id)
+}
+
+// UseTOTPCounter saves time step of accepted TOTP code of user in pgdb, concurrent requests can't accept
+// codes of the same step
+func (c *PostgresRepository) UseTOTPCounter(ctx context.Context, id uuid.UUID, counter int64) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
+
+	tag, err := c.conn.Exec(ctx, "UPDATE users SET totp_last_counter = $1 WHERE id = $2 AND totp_last_counter < $1",
+		counter, id)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTOTPCodeUsed
+	}
+	return nil
+}
+
+// UseRecoveryCode removes recovery code of user from pgdb, the code can't be used twice by concurrent requests
+func (c *PostgresRepository) UseRecoveryCode(ctx context.Context, id uuid.UUID, hash string) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
+
+	tag, err := c.conn.Exec(ctx, "UPDATE users SET recovery_codes = array_remove(recovery_codes, $1) "+
+		"WHERE id = $2 AND $1 = ANY(recovery_codes)", hash, id)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+	return nil
+}
+
+// updateUser executes update of a single user in pgdb, it returns ErrUserNotFound if nothing is updated
+func (c *PostgresRepository) updateUser(ctx context.Context, sql string, args ...interface{}) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
+
+	tag, err := c.conn.Exec(ctx, sql, args...)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// SetTOTPSecret saves unconfirmed TOTP secret of user in mongodb
+func (c *MongoRepository) SetTOTPSecret(ctx context.Context, id uuid.UUID, secret string) error {
+	return c.updateUser(ctx, id, bson.D{primitive.E{Key: "$set", Value: bson.D{
+		{Key: "totp_secret", Value: secret},
+		{Key: "totp_enabled", Value: false},
+		{Key: "recovery_codes", Value: []string{}},
+	}}})
+}
+
+// EnableTOTP turns on TOTP of user in mongodb
+func (c *MongoRepository) EnableTOTP(ctx context.Context, id uuid.UUID, recoveryCodes []string) error {
+	return c.updateUser(ctx, id, bson.D{primitive.E{Key: "$set", Value: bson.D{
+		{Key: "totp_enabled", Value: true},
+		{Key: "recovery_codes", Value: recoveryCodes},
+	}}})
+}
+
+// DisableTOTP turns off TOTP of user in mongodb
+func (c *MongoRepository) DisableTOTP(ctx context.Context, id uuid.UUID) error {
+	return c.updateUser(ctx, id, bson.D{primitive.E{Key: "$set", Value: bson.D{
+		{Key: "totp_secret", Value: ""},
+		{Key: "totp_enabled", Value: false},
+		{Key: "recovery_codes", Value: []string{}},
+	}}})
+}
+
+// UseTOTPCounter saves time step of accepted TOTP code of user in mongodb, concurrent requests can't accept
+// codes of the same step, users without saved step match too
+func (c *MongoRepository) UseTOTPCounter(ctx context.Context, id uuid.UUID, counter int64) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.MongoTimeout)
+	defer cancel()
+
+	res, err := c.users().UpdateOne(ctx,
+		bson.D{
+			{Key: "id", Value: id},
+			{Key: "totp_last_counter", Value: bson.D{primitive.E{Key: "$not", Value: bson.D{
+				primitive.E{Key: "$gte", Value: counter}}}}},
+		},
+		bson.D{primitive.E{Key: "$set", Value: bson.D{primitive.E{Key: "totp_last_counter", Value: counter}}}})
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrTOTPCodeUsed
+	}
+	return nil
+}
+
+// UseRecoveryCode removes recovery code of user from mongodb, the code can't be used twice by concurrent requests
+func (c *MongoRepository) UseRecoveryCode(ctx context.Context, id uuid.UUID, hash string) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.MongoTimeout)
+	defer cancel()
+
+	res, err := c.users().UpdateOne(ctx,
+		bson.D{{Key: "id", Value: id}, {Key: "recovery_codes", Value: hash}},
+		bson.D{primitive.E{Key: "$pull", Value: bson.D{primitive.E{Key: "recovery_codes", Value: hash}}}})
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+	return nil
+}
+
+// SetTOTPSecret saves unconfirmed TOTP secret of user
+func (c *MemoryRepository) SetTOTPSecret(ctx context.Context, id uuid.UUID, secret string) error {
+	return c.updateUser(ctx, id, func(user *models.User) error {
+		user.TOTPSecret, user.TOTPEnabled, user.RecoveryCodes = secret, false, nil
+		return nil
+	})
+}
+
+// EnableTOTP turns on TOTP of user
+func (c *MemoryRepository) EnableTOTP(ctx context.Context, id uuid.UUID, recoveryCodes []string) error {
+	return c.updateUser(ctx, id, func(user *models.User) error {
+		user.TOTPEnabled, user.RecoveryCodes = true, append([]string(nil), recoveryCodes...)
+		return nil
+	})
+}
+
+// DisableTOTP turns off TOTP of user
+func (c *MemoryRepository) DisableTOTP(ctx context.Context, id uuid.UUID) error {
+	return c.updateUser(ctx, id, func(user *models.User) error {
+		user.TOTPSecret, user.TOTPEnabled, user.RecoveryCodes = "", false, nil
+		return nil
+	})
+}
+
+// UseTOTPCounter saves time step of accepted TOTP code of user
+func (c *MemoryRepository) UseTOTPCounter(ctx context.Context, id uuid.UUID, counter int64) error {
+	return c.updateUser(ctx, id, func(user *models.User) error {
+		if counter <= user.TOTPLastCounter {
+			return ErrTOTPCodeUsed
+		}
+		user.TOTPLastCounter = counter
+		return nil
+	})
+}
+
+// UseRecoveryCode removes recovery code of user
+func (c *MemoryRepository) UseRecoveryCode(ctx context.Context, id uuid.UUID, hash string) error {
+	return c.updateUser(ctx, id, func(user *models.User) error {
+		// codes are copied since users returned before share the slice
+		codes := make([]string, 0, len(user.RecoveryCodes))
+		for _, code := range user.RecoveryCodes {
+			if code != hash {
+				codes = append(codes, code)
+			}
+		}
+		if len(codes) == len(user.RecoveryCodes) {
+			return ErrRecoveryCodeNotFound
+		}
+		user.RecoveryCodes = codes
+		return nil
+	})
+}
+
+// updateUser applies 'update' to user with 'id', user isn't changed if 'update' fails
+func (c *MemoryRepository) updateUser(ctx context.Context, id uuid.UUID, update func(user *models.User) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for username, user := range c.users {
+		if user.ID == id {
+			if err := update(&user); err != nil {
+				return err
+			}
+			c.users[username] = user
+			return nil
+		}
+	}
+	return ErrUserNotFound
+}
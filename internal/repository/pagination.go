@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"CatsGo/internal/apperror"
+	"CatsGo/internal/models"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidCursor is returned when cursor of cats listing is malformed or belongs to another sort order
+	ErrInvalidCursor = apperror.New(apperror.Validation, "invalid cursor")
+	// ErrInvalidSort is returned when cats listing is requested in unsupported order
+	ErrInvalidSort = apperror.New(apperror.Validation, "invalid sort order")
+)
+
+// catsCursor points to the last cat of a page, 'id' breaks ties between equal sort keys
+type catsCursor struct {
+	Sort      string    `json:"s"`
+	Name      string    `json:"n,omitempty"`
+	CreatedAt time.Time `json:"c"`
+	ID        uuid.UUID `json:"i"`
+}
+
+// catsSort returns field name and direction of cats listing order
+func catsSort(sort string) (field string, desc bool, err error) {
+	switch sort {
+	case models.SortByCreatedAt:
+		return "created_at", false, nil
+	case models.SortByCreatedAtDesc:
+		return "created_at", true, nil
+	case models.SortByName:
+		return "name", false, nil
+	case models.SortByNameDesc:
+		return "name", true, nil
+	}
+	return "", false, ErrInvalidSort
+}
+
+// prepareCatsQuery sets default sort order of query and decodes its cursor
+func prepareCatsQuery(query *models.CatsQuery) (field string, desc bool, cur *catsCursor, err error) {
+	if query.Sort == "" {
+		query.Sort = models.SortByCreatedAt
+	}
+	if field, desc, err = catsSort(query.Sort); err != nil {
+		return "", false, nil, err
+	}
+	if cur, err = decodeCatsCursor(query.Cursor, query.Sort); err != nil {
+		return "", false, nil, err
+	}
+	return field, desc, cur, nil
+}
+
+func encodeCatsCursor(sort string, cat *models.Cats) string {
+	cur := catsCursor{Sort: sort, ID: cat.ID}
+	if field, _, _ := catsSort(sort); field == "name" {
+		cur.Name = cat.Name
+	} else {
+		cur.CreatedAt = cat.CreatedAt
+	}
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCatsCursor returns nil if cursor isn't set
+func decodeCatsCursor(s, sort string) (*catsCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cur catsCursor
+	if err := json.Unmarshal(data, &cur); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cur.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+	return &cur, nil
+}
+
+// escapeLike escapes wildcards of LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// matchCatsQuery reports whether cat passes name filters of query
+func matchCatsQuery(cat *models.Cats, query *models.CatsQuery) bool {
+	name := strings.ToLower(cat.Name)
+	if query.NamePrefix != "" && !strings.HasPrefix(name, strings.ToLower(query.NamePrefix)) {
+		return false
+	}
+	if query.NameContains != "" && !strings.Contains(name, strings.ToLower(query.NameContains)) {
+		return false
+	}
+	if query.OwnerID != nil && (cat.OwnerID == nil || *cat.OwnerID != *query.OwnerID) {
+		return false
+	}
+	return true
+}
+
+// compareCats compares cats by sort field and then by 'id', like databases do
+func compareCats(a, b *models.Cats, field string) int {
+	var cmp int
+	if field == "name" {
+		cmp = strings.Compare(a.Name, b.Name)
+	} else {
+		switch {
+		case a.CreatedAt.Before(b.CreatedAt):
+			cmp = -1
+		case a.CreatedAt.After(b.CreatedAt):
+			cmp = 1
+		}
+	}
+	if cmp != 0 {
+		return cmp
+	}
+	return bytes.Compare(a.ID[:], b.ID[:])
+}
+
+// newCatsPage cuts extra cat fetched to detect the next page and sets NextCursor
+func newCatsPage(cats []*models.Cats, total int64, query *models.CatsQuery) *models.CatsPage {
+	page := &models.CatsPage{Cats: cats, Total: total}
+	if query.Limit > 0 && len(cats) > query.Limit {
+		page.Cats = cats[:query.Limit]
+		page.NextCursor = encodeCatsCursor(query.Sort, page.Cats[query.Limit-1])
+	}
+	return page
+}
@@ -2,59 +2,101 @@
 package repository
 
 import (
+	"CatsGo/internal/configs"
 	"CatsGo/internal/models"
 	"context"
+	"errors"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 )
 
-// RedisRepository provides a connection with redis
+// NewRedisClient provides connection with redis
+func NewRedisClient(cfg *configs.Config) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisHost + ":" + cfg.RedisPort,
+		Password: "", // no password set
+		DB:       0,  // use default DB
+	})
+}
+
+// RedisRepository provides a cache of cats in redis
 type RedisRepository struct {
-	rdb *redis.Client
+	cache *RedisCache
 }
 
 // NewRedisRepository is constructor
-func NewRedisRepository(rdb *redis.Client) *RedisRepository {
-	return &RedisRepository{rdb: rdb}
+func NewRedisRepository(rdb *redis.Client, cfg *configs.Config) *RedisRepository {
+	return &RedisRepository{
+		cache: NewRedisCache(rdb, RedisCacheOptions{
+			Prefix:           "cat:",
+			Timeout:          cfg.RedisTimeout,
+			TTL:              cfg.CacheTTL,
+			NegativeTTL:      cfg.CacheNegativeTTL,
+			LockTTL:          cfg.CacheLockTTL,
+			EarlyRefreshBeta: cfg.CacheEarlyRefreshBeta,
+		}),
+	}
 }
 
-// CreateCat provides request to create new cat in redis database
-func (c *RedisRepository) CreateCat(cat models.Cats) error {
-	ctx := context.TODO()
-	cat.ID = uuid.New()
-	catID := cat.ID.String()
-	args := catID + ":" + cat.Name
-
-	err := c.rdb.Set(ctx, catID, args, 0).Err()
+// SetCat provides request to save cat with its 'id' in redis database,
+// 'delta' is a time spent to read cat from database
+func (c *RedisRepository) SetCat(ctx context.Context, cat models.Cats, delta time.Duration) error {
+	err := c.cache.Set(ctx, cat.ID.String(), cat, delta)
 	if err != nil {
-		log.Error("redis error while creating a cat")
+		log.Error("redis error while saving a cat")
 		return err
 	}
 	return nil
 }
 
-// GetCat provides request to get cat by 'id' from redis database
-func (c *RedisRepository) GetCat(id uuid.UUID) (*models.Cats, error) {
-	ctx := context.TODO()
-	catID := id.String()
-	val, err := c.rdb.Get(ctx, catID).Result()
+// GetCat provides request to get cat by 'id' from redis database, it returns ErrCacheMiss
+// if cat isn't cached and ErrCatNotFound if cat is cached as missing in database
+func (c *RedisRepository) GetCat(ctx context.Context, id uuid.UUID) (*models.Cats, error) {
+	var cat models.Cats
+
+	err := c.cache.Get(ctx, id.String(), &cat)
+	if errors.Is(err, ErrCachedNotFound) {
+		return nil, ErrCatNotFound
+	}
 	if err != nil {
-		log.Error("redis error no such a cat in database")
+		if !errors.Is(err, ErrCacheMiss) {
+			log.Error("redis error while getting a cat")
+		}
 		return nil, err
 	}
-	return &models.Cats{ID: id, Name: val}, nil
+	return &cat, nil
 }
 
-// DeleteCat provides request to delete cat by 'id' from redis database
-func (c *RedisRepository) DeleteCat(id uuid.UUID) error {
-	ctx := context.TODO()
+// CatNotFound provides request to remember in redis database that cat with 'id' doesn't exist
+func (c *RedisRepository) CatNotFound(ctx context.Context, id uuid.UUID) error {
+	err := c.cache.SetNotFound(ctx, id.String())
+	if err != nil {
+		log.Error("redis error while saving a missing cat")
+		return err
+	}
+	return nil
+}
 
-	_, err := c.rdb.Del(ctx, id.String()).Result()
+// DeleteCat provides request to delete cat by 'id' from redis database
+func (c *RedisRepository) DeleteCat(ctx context.Context, id uuid.UUID) error {
+	err := c.cache.Delete(ctx, id.String())
 	if err != nil {
 		log.Error("redis error while deleting a cat")
 		return err
 	}
 	return nil
 }
+
+// LockCat takes lock for reading cat by 'id' from database, it returns ErrLocked
+// if another instance of app is reading it
+func (c *RedisRepository) LockCat(ctx context.Context, id uuid.UUID) (unlock func(), err error) {
+	return c.cache.Lock(ctx, id.String())
+}
+
+// Stats returns counters of cache lookups
+func (c *RedisRepository) Stats() CacheStats {
+	return c.cache.Stats()
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"CatsGo/internal/configs"
+	"CatsGo/internal/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRedisConfig = &configs.Config{
+	RedisTimeout:     time.Second,
+	CacheTTL:         time.Minute,
+	CacheNegativeTTL: 10 * time.Second,
+	CacheLockTTL:     time.Second,
+}
+
+func newTestRedisRepository(t *testing.T) (*RedisRepository, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return NewRedisRepository(rdb, testRedisConfig), mr
+}
+
+func TestRedisRepository_Cats(t *testing.T) {
+	ctx := context.Background()
+	birthDate := models.NewDate(time.Date(2020, time.May, 1, 0, 0, 0, 0, time.UTC))
+	ownerID := uuid.New()
+	cat := models.Cats{ID: uuid.New(), Name: "Barsik", Breed: "Siberian", BirthDate: &birthDate,
+		Sex: models.CatSexMale, Weight: 4.5, Status: models.CatStatusAvailable, OwnerID: &ownerID,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond), UpdatedAt: time.Now().UTC().Truncate(time.Microsecond)}
+
+	TestTable := []struct {
+		name        string
+		prepare     func(rds *RedisRepository) error
+		exceptCat   *models.Cats
+		exceptError error
+		exceptTTL   time.Duration
+		exceptStats CacheStats
+	}{
+		{
+			name:        "miss",
+			prepare:     func(rds *RedisRepository) error { return nil },
+			exceptError: ErrCacheMiss,
+			exceptStats: CacheStats{Misses: 1},
+		},
+		{
+			name:        "hit keeps all fields",
+			prepare:     func(rds *RedisRepository) error { return rds.SetCat(ctx, cat, time.Millisecond) },
+			exceptCat:   &cat,
+			exceptTTL:   testRedisConfig.CacheTTL,
+			exceptStats: CacheStats{Hits: 1},
+		},
+		{
+			name:        "negative entry",
+			prepare:     func(rds *RedisRepository) error { return rds.CatNotFound(ctx, cat.ID) },
+			exceptError: ErrCatNotFound,
+			exceptTTL:   testRedisConfig.CacheNegativeTTL,
+			exceptStats: CacheStats{Hits: 1},
+		},
+		{
+			name: "invalidated",
+			prepare: func(rds *RedisRepository) error {
+				if err := rds.SetCat(ctx, cat, time.Millisecond); err != nil {
+					return err
+				}
+				return rds.DeleteCat(ctx, cat.ID)
+			},
+			exceptError: ErrCacheMiss,
+			exceptStats: CacheStats{Misses: 1},
+		},
+		{
+			name: "invalidated negative entry",
+			prepare: func(rds *RedisRepository) error {
+				if err := rds.CatNotFound(ctx, cat.ID); err != nil {
+					return err
+				}
+				return rds.DeleteCat(ctx, cat.ID)
+			},
+			exceptError: ErrCacheMiss,
+			exceptStats: CacheStats{Misses: 1},
+		},
+	}
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			rds, mr := newTestRedisRepository(t)
+			require.NoError(t, TestCase.prepare(rds))
+
+			got, err := rds.GetCat(ctx, cat.ID)
+			if TestCase.exceptError != nil {
+				assert.ErrorIs(t, err, TestCase.exceptError)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, TestCase.exceptCat, got)
+			}
+			assert.Equal(t, TestCase.exceptTTL, mr.TTL("cat:"+cat.ID.String()))
+			assert.Equal(t, TestCase.exceptStats, rds.Stats())
+		})
+	}
+}
+
+func TestRedisRepository_Expiration(t *testing.T) {
+	ctx := context.Background()
+	rds, mr := newTestRedisRepository(t)
+	id := uuid.New()
+
+	require.NoError(t, rds.CatNotFound(ctx, id))
+	mr.FastForward(testRedisConfig.CacheNegativeTTL)
+	_, err := rds.GetCat(ctx, id)
+	assert.ErrorIs(t, err, ErrCacheMiss, "negative entries expire")
+
+	require.NoError(t, rds.SetCat(ctx, models.Cats{ID: id, Name: "Barsik"}, time.Millisecond))
+	mr.FastForward(testRedisConfig.CacheTTL)
+	_, err = rds.GetCat(ctx, id)
+	assert.ErrorIs(t, err, ErrCacheMiss, "values expire")
+	assert.Equal(t, CacheStats{Misses: 2}, rds.Stats())
+}
+
+func TestRedisRepository_UnknownFormat(t *testing.T) {
+	ctx := context.Background()
+	rds, mr := newTestRedisRepository(t)
+	id := uuid.New()
+
+	// values written before JSON entries are refreshed
+	require.NoError(t, mr.Set("cat:"+id.String(), id.String()+":Barsik"))
+	_, err := rds.GetCat(ctx, id)
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+func TestRedisRepository_LockCat(t *testing.T) {
+	ctx := context.Background()
+	rds, mr := newTestRedisRepository(t)
+	id := uuid.New()
+
+	unlock, err := rds.LockCat(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, testRedisConfig.CacheLockTTL, mr.TTL("lock:cat:"+id.String()))
+
+	_, err = rds.LockCat(ctx, id)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	unlock()
+	assert.False(t, mr.Exists("lock:cat:"+id.String()))
+	unlock, err = rds.LockCat(ctx, id)
+	require.NoError(t, err)
+	unlock()
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"CatsGo/internal/configs"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Backend contains repositories provided by one storage backend
+type Backend struct {
+	Repository Repository
+	Auth       Auth
+	// Tokens and Attempts keep short-lived state of authentication, it's shared by all instances of app
+	// unless backend keeps it in memory
+	Tokens   TokenStore
+	Attempts LoginAttempts
+	// Close releases connections held by the backend
+	Close func()
+}
+
+// Factory opens a storage backend using app configuration
+type Factory func(ctx context.Context, cfg *configs.Config) (*Backend, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{
+		"postgres": openPostgres,
+		"mongodb":  openMongo,
+		"memory":   openMemory,
+	}
+)
+
+// Register makes a storage backend available by name, it panics on duplicate names
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if factory == nil {
+		panic("repository: Register factory is nil")
+	}
+	if _, dup := factories[name]; dup {
+		panic("repository: Register called twice for backend " + name)
+	}
+	factories[name] = factory
+}
+
+// Backends returns sorted names of all registered storage backends
+func Backends() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open creates repositories of the storage backend registered with 'name'
+func Open(ctx context.Context, name string, cfg *configs.Config) (*Backend, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown storage backend %q, available: %v", name, Backends())
+	}
+	backend, err := factory(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if backend.Repository == nil || backend.Auth == nil || backend.Tokens == nil || backend.Attempts == nil {
+		if backend.Close != nil {
+			backend.Close()
+		}
+		return nil, fmt.Errorf("storage backend %q doesn't provide all repositories", name)
+	}
+	return backend, nil
+}
+
+// openRedisStores keeps state of authentication in redis for backends shared by instances of app,
+// 'closeFn' of backend is extended to close connection with redis
+func openRedisStores(backend *Backend, cfg *configs.Config, closeFn func()) *Backend {
+	rdb := NewRedisClient(cfg)
+	backend.Tokens = NewRedisTokenStore(rdb, cfg.RedisTimeout)
+	backend.Attempts = NewRedisLoginAttempts(rdb, cfg.RedisTimeout)
+	backend.Close = func() {
+		closeFn()
+		if err := rdb.Close(); err != nil {
+			log.Error(err)
+		}
+	}
+	return backend
+}
+
+func openPostgres(ctx context.Context, cfg *configs.Config) (*Backend, error) {
+	conn, err := NewPgxPool(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	rps := NewPostgresRepository(conn, cfg)
+	return openRedisStores(&Backend{Repository: rps, Auth: rps}, cfg, conn.Close), nil
+}
+
+func openMemory(_ context.Context, _ *configs.Config) (*Backend, error) {
+	rps := NewMemoryRepository()
+	return &Backend{Repository: rps, Auth: rps, Tokens: NewMemoryTokenStore(), Attempts: NewMemoryLoginAttempts(),
+		Close: func() {}}, nil
+}
+
+func openMongo(ctx context.Context, cfg *configs.Config) (*Backend, error) {
+	client, err := NewMongoClient(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	closeFn := func() {
+		if err := client.Disconnect(context.Background()); err != nil {
+			log.Error(err)
+		}
+	}
+	rps := NewMongoRepository(client, cfg)
+	if err := rps.createCatsIndexes(ctx); err != nil {
+		log.Errorf("unable to create indexes of cats in mongo database: %v\n", err)
+		closeFn()
+		return nil, fmt.Errorf("we can't prepare mongo database")
+	}
+	if err := rps.createUsersIndex(ctx); err != nil {
+		log.Errorf("unable to create index of users in mongo database: %v\n", err)
+		closeFn()
+		return nil, fmt.Errorf("we can't prepare mongo database")
+	}
+	if err := rps.createAPIKeysIndex(ctx); err != nil {
+		log.Errorf("unable to create index of api keys in mongo database: %v\n", err)
+		closeFn()
+		return nil, fmt.Errorf("we can't prepare mongo database")
+	}
+	return openRedisStores(&Backend{Repository: rps, Auth: rps}, cfg, closeFn), nil
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"CatsGo/internal/configs"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen(t *testing.T) {
+	closed := false
+	Register("test-without-stores", func(_ context.Context, _ *configs.Config) (*Backend, error) {
+		rps := NewMemoryRepository()
+		return &Backend{Repository: rps, Auth: rps, Close: func() { closed = true }}, nil
+	})
+	ctx := context.Background()
+
+	backend, err := Open(ctx, "memory", &configs.Config{})
+	require.NoError(t, err)
+	defer backend.Close()
+	assert.IsType(t, &MemoryTokenStore{}, backend.Tokens)
+	assert.IsType(t, &MemoryLoginAttempts{}, backend.Attempts)
+
+	_, err = Open(ctx, "test-without-stores", &configs.Config{})
+	assert.Error(t, err, "backend has to provide all repositories")
+	assert.True(t, closed)
+
+	_, err = Open(ctx, "unknown", &configs.Config{})
+	assert.Error(t, err)
+}
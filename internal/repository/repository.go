@@ -2,23 +2,31 @@
 package repository
 
 import (
+	"CatsGo/internal/apperror"
 	"CatsGo/internal/configs"
 	"CatsGo/internal/models"
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	log "github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // PostgresRepository provides a connection with pgsql
 type PostgresRepository struct {
 	conn *pgxpool.Pool
+	cfg  *configs.Config
 }
 
 // MongoRepository provides a connection with mongodb
@@ -27,18 +35,58 @@ type MongoRepository struct {
 	cfg    *configs.Config
 }
 
+// ErrCatNotFound is returned when requested cat is missing in database
+var ErrCatNotFound = apperror.New(apperror.NotFound, "cat doesn't exist in database")
+
 // Repository contains methods for work with cats collection
 type Repository interface {
-	GetAllCats() ([]*models.Cats, error)
-	CreateCat(cats models.Cats) (*models.Cats, error)
-	GetCat(id uuid.UUID) (*models.Cats, error)
-	UpdateCat(id uuid.UUID, cats models.Cats) (*models.Cats, error)
-	DeleteCat(id uuid.UUID) error
+	GetAllCats(ctx context.Context, query models.CatsQuery) (*models.CatsPage, error)
+	CreateCat(ctx context.Context, cats models.Cats) (*models.Cats, error)
+	GetCat(ctx context.Context, id uuid.UUID) (*models.Cats, error)
+	UpdateCat(ctx context.Context, id uuid.UUID, cats models.Cats) (*models.Cats, error)
+	DeleteCat(ctx context.Context, id uuid.UUID) error
+	SearchCats(ctx context.Context, query string, limit int) ([]*models.Cats, error)
+}
+
+// NewPgxPool provides connection with postgres database
+func NewPgxPool(ctx context.Context, cfg *configs.Config) (*pgxpool.Pool, error) {
+	url := fmt.Sprintf("postgres://%s:%s@%s:%s/%s",
+		cfg.PgUser,
+		cfg.PgPassword,
+		cfg.PgHost,
+		cfg.PgPort,
+		cfg.PgDBName)
+	conn, cfgErr := pgxpool.Connect(ctx, url)
+	if cfgErr != nil {
+		log.Errorf("unable to connect to postgres database: %v\n", cfgErr)
+		return nil, fmt.Errorf("we can't connect to postgres database")
+	}
+	return conn, nil
+}
+
+// NewMongoClient provides connection with mongo database
+func NewMongoClient(ctx context.Context, cfg *configs.Config) (*mongo.Client, error) {
+	url := fmt.Sprintf("mongodb://%s:%s@%s:%s",
+		cfg.MongoUser,
+		cfg.MongoPassword,
+		cfg.MongoHost,
+		cfg.MongoPort)
+	client, err := mongo.NewClient(options.Client().ApplyURI(url).SetRegistry(mongoRegistry))
+	if err != nil {
+		log.Error(err)
+		return nil, fmt.Errorf("we can't setup connection with mongo database")
+	}
+	err = client.Connect(ctx)
+	if err != nil {
+		log.Errorf("unable to connect to mongo database: %v\n", err)
+		return nil, fmt.Errorf("we can't connect to mongo database")
+	}
+	return client, nil
 }
 
 // NewPostgresRepository creates new cats repository
-func NewPostgresRepository(conn *pgxpool.Pool) *PostgresRepository {
-	return &PostgresRepository{conn: conn}
+func NewPostgresRepository(conn *pgxpool.Pool, cfg *configs.Config) *PostgresRepository {
+	return &PostgresRepository{conn: conn, cfg: cfg}
 }
 
 // NewMongoRepository creates new cats repository
@@ -46,142 +94,488 @@ func NewMongoRepository(client *mongo.Client, cfg *configs.Config) *MongoReposit
 	return &MongoRepository{client: client, cfg: cfg}
 }
 
-// GetAllCats provides request to get all cats from pgdb
-func (c *PostgresRepository) GetAllCats() ([]*models.Cats, error) {
-	var allcats []*models.Cats
+// withTimeout limits an operation on database by 'timeout' if it's set
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// catColumns lists columns of cats table in order of scanCat
+const catColumns = "id, name, breed, birth_date, sex, color, weight, status, owner_id, created_at, updated_at"
+
+// scanCat reads a row of catColumns into cat
+func scanCat(row pgx.Row, cat *models.Cats) error {
+	var birthDate *time.Time
+	err := row.Scan(&cat.ID, &cat.Name, &cat.Breed, &birthDate, &cat.Sex, &cat.Color, &cat.Weight,
+		&cat.Status, &cat.OwnerID, &cat.CreatedAt, &cat.UpdatedAt)
+	if err == nil && birthDate != nil {
+		date := models.NewDate(*birthDate)
+		cat.BirthDate = &date
+	}
+	return err
+}
 
-	rows, err := c.conn.Query(context.Background(), "SELECT id, name FROM cats")
+// dateValue returns date as value of DATE column, nil date is NULL
+func dateValue(date *models.Date) *time.Time {
+	if date == nil {
+		return nil
+	}
+	return &date.Time
+}
+
+// GetAllCats provides request to get a page of cats from pgdb
+func (c *PostgresRepository) GetAllCats(ctx context.Context, query models.CatsQuery) (*models.CatsPage, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
+
+	field, desc, cur, err := prepareCatsQuery(&query)
+	if err != nil {
+		return nil, err
+	}
+	// names are compared bytewise to keep the same order as in mongodb
+	if field == "name" {
+		field = `name COLLATE "C"`
+	}
+
+	var (
+		where []string
+		args  []interface{}
+	)
+	if query.NamePrefix != "" {
+		args = append(args, escapeLike(query.NamePrefix)+"%")
+		where = append(where, fmt.Sprintf("name ILIKE $%d", len(args)))
+	}
+	if query.NameContains != "" {
+		args = append(args, "%"+escapeLike(query.NameContains)+"%")
+		where = append(where, fmt.Sprintf("name ILIKE $%d", len(args)))
+	}
+	if query.OwnerID != nil {
+		args = append(args, *query.OwnerID)
+		where = append(where, fmt.Sprintf("owner_id = $%d", len(args)))
+	}
+
+	var total int64
+	err = c.conn.QueryRow(ctx, "SELECT count(*) FROM cats"+whereClause(where), args...).Scan(&total)
 	if err != nil {
 		log.Error(err)
+		return nil, err
+	}
+
+	order, cmp := "ASC", ">"
+	if desc {
+		order, cmp = "DESC", "<"
 	}
+	if cur != nil {
+		if field == "created_at" {
+			args = append(args, cur.CreatedAt, cur.ID)
+		} else {
+			args = append(args, cur.Name, cur.ID)
+		}
+		where = append(where, fmt.Sprintf("(%s, id) %s ($%d, $%d)", field, cmp, len(args)-1, len(args)))
+	}
+	sql := fmt.Sprintf("SELECT %s FROM cats%s ORDER BY %s %s, id %s",
+		catColumns, whereClause(where), field, order, order)
+	if query.Limit > 0 {
+		args = append(args, query.Limit+1)
+		sql += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if cur == nil && query.Offset > 0 {
+		args = append(args, query.Offset)
+		sql += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := c.conn.Query(ctx, sql, args...)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	allcats := make([]*models.Cats, 0, query.Limit+1)
 	for rows.Next() {
 		var cat models.Cats
 
-		if err := rows.Scan(&cat.ID, &cat.Name); err != nil {
+		if err := scanCat(rows, &cat); err != nil {
 			log.Error("failed to return all cats from database")
 			return nil, err
 		}
 
 		allcats = append(allcats, &cat)
 	}
-	return allcats, nil
+	if err := rows.Err(); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	return newCatsPage(allcats, total, &query), nil
+}
+
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
 }
 
 // CreateCat provides request to create new cat in pgdb
-func (c *PostgresRepository) CreateCat(cat models.Cats) (*models.Cats, error) {
+func (c *PostgresRepository) CreateCat(ctx context.Context, cat models.Cats) (*models.Cats, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
+
 	cat.ID = uuid.New()
-	result, err := c.conn.Exec(context.Background(), "INSERT INTO cats VALUES ($1, $2)", cat.ID, cat.Name)
+	err := c.conn.QueryRow(ctx, "INSERT INTO cats (id, name, breed, birth_date, sex, color, weight, status, owner_id) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING created_at, updated_at",
+		cat.ID, cat.Name, cat.Breed, dateValue(cat.BirthDate), cat.Sex, cat.Color, cat.Weight, cat.Status, cat.OwnerID).
+		Scan(&cat.CreatedAt, &cat.UpdatedAt)
 	if err != nil {
 		log.Error(err)
 		return &cat, err
 	}
-	if result.RowsAffected() != 1 {
-		log.Error("failed to create a cat")
-		return &cat, err
-	}
 	return &cat, nil
 }
 
 // GetCat provides request to get cat by 'id' from pgdb
-func (c *PostgresRepository) GetCat(id uuid.UUID) (*models.Cats, error) {
+func (c *PostgresRepository) GetCat(ctx context.Context, id uuid.UUID) (*models.Cats, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
+
 	var cat models.Cats
 
-	result := c.conn.QueryRow(context.Background(), "SELECT * FROM cats WHERE id=$1", id)
-	err := result.Scan(&cat.ID, &cat.Name)
+	result := c.conn.QueryRow(ctx, "SELECT "+catColumns+" FROM cats WHERE id=$1", id)
+	err := scanCat(result, &cat)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCatNotFound
+	}
 	if err != nil {
 		log.Error(err)
-		err = errors.New("cat doesn't exist in database")
 		return nil, err
 	}
 	return &cat, nil
 }
 
 // UpdateCat provides request to update cat by 'id' in pgdb
-func (c *PostgresRepository) UpdateCat(id uuid.UUID, cats models.Cats) (*models.Cats, error) {
-	result, err := c.conn.Exec(context.Background(), "UPDATE cats SET name = $1 WHERE id = $2", cats.Name, id)
+func (c *PostgresRepository) UpdateCat(ctx context.Context, id uuid.UUID, cats models.Cats) (*models.Cats, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
+
+	var cat models.Cats
+
+	result := c.conn.QueryRow(ctx, "UPDATE cats SET name = $1, breed = $2, birth_date = $3, sex = $4, color = $5, "+
+		"weight = $6, status = $7, updated_at = now() WHERE id = $8 RETURNING "+catColumns,
+		cats.Name, cats.Breed, dateValue(cats.BirthDate), cats.Sex, cats.Color, cats.Weight, cats.Status, id)
+	err := scanCat(result, &cat)
+	if errors.Is(err, pgx.ErrNoRows) {
+		log.Error("row isn't updated")
+		return &cats, ErrCatNotFound
+	}
 	if err != nil {
 		log.Error(err)
 		return &cats, err
 	}
-	if result.RowsAffected() != 1 {
-		log.Error("row isn't updated")
-		err = errors.New("cat doesn't exist in database")
-		return &cats, err
-	}
-	return &cats, nil
+	return &cat, nil
 }
 
 // DeleteCat provides request to delete cat by 'id' from pgdb
-func (c *PostgresRepository) DeleteCat(id uuid.UUID) error {
-	_, err := c.conn.Exec(context.Background(), "DELETE FROM cats WHERE id=$1", id)
+func (c *PostgresRepository) DeleteCat(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
+
+	tag, err := c.conn.Exec(ctx, "DELETE FROM cats WHERE id=$1", id)
 	if err != nil {
 		log.Error("error while deleting a cat")
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return ErrCatNotFound
+	}
 	return nil
 }
 
-// GetAllCats provides request to get all cats from mongodb
-func (c *MongoRepository) GetAllCats() ([]*models.Cats, error) {
-	var allcats []*models.Cats
+// SearchCats provides full-text and fuzzy search of cats by name in pgdb, best matches go first
+func (c *PostgresRepository) SearchCats(ctx context.Context, query string, limit int) ([]*models.Cats, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.PgTimeout)
+	defer cancel()
 
-	collection := c.client.Database(c.cfg.MongoDBName).Collection(c.cfg.MongoCollection)
-	cur, currErr := collection.Find(context.TODO(), bson.D{})
-	if currErr != nil {
-		panic(currErr)
+	rows, err := c.conn.Query(ctx, "SELECT "+catColumns+" FROM cats "+
+		"WHERE to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR name % $1 "+
+		"ORDER BY ts_rank(to_tsvector('simple', name), plainto_tsquery('simple', $1)) + similarity(name, $1) DESC, id "+
+		"LIMIT $2", query, limit)
+	if err != nil {
+		log.Error(err)
+		return nil, err
 	}
-	if err := cur.Close(context.TODO()); err != nil {
-		panic(err)
+	defer rows.Close()
+
+	allcats := make([]*models.Cats, 0, limit)
+	for rows.Next() {
+		var cat models.Cats
+
+		if err := scanCat(rows, &cat); err != nil {
+			log.Error("failed to search cats in database")
+			return nil, err
+		}
+
+		allcats = append(allcats, &cat)
 	}
-	if err := cur.All(context.TODO(), &allcats); err != nil {
-		panic(err)
+	if err := rows.Err(); err != nil {
+		log.Error(err)
+		return nil, err
 	}
 	return allcats, nil
 }
 
-// CreateCat provides request to create cat in mongodb
-func (c *MongoRepository) CreateCat(cats models.Cats) (*models.Cats, error) {
-	collection := c.client.Database(c.cfg.MongoDBName).Collection(c.cfg.MongoCollection)
-	docs := []interface{}{
-		bson.D{primitive.E{Key: "id", Value: cats.ID}, {Key: "name", Value: cats.Name}},
+// GetAllCats provides request to get a page of cats from mongodb
+func (c *MongoRepository) GetAllCats(ctx context.Context, query models.CatsQuery) (*models.CatsPage, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.MongoTimeout)
+	defer cancel()
+
+	field, desc, cur, err := prepareCatsQuery(&query)
+	if err != nil {
+		return nil, err
 	}
-	_, insertErr := collection.InsertMany(context.TODO(), docs)
-	if insertErr != nil {
-		log.Fatal(insertErr)
+
+	filter := bson.A{}
+	if query.NamePrefix != "" {
+		filter = append(filter, bson.D{primitive.E{Key: "name", Value: primitive.Regex{
+			Pattern: "^" + regexp.QuoteMeta(query.NamePrefix), Options: "i"}}})
+	}
+	if query.NameContains != "" {
+		filter = append(filter, bson.D{primitive.E{Key: "name", Value: primitive.Regex{
+			Pattern: regexp.QuoteMeta(query.NameContains), Options: "i"}}})
+	}
+	if query.OwnerID != nil {
+		filter = append(filter, bson.D{primitive.E{Key: "owner_id", Value: *query.OwnerID}})
+	}
+
+	collection := c.cats()
+	total, err := collection.CountDocuments(ctx, mongoAnd(filter))
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	order, cmp := 1, "$gt"
+	if desc {
+		order, cmp = -1, "$lt"
+	}
+	if cur != nil {
+		var value interface{} = cur.Name
+		if field == "created_at" {
+			value = cur.CreatedAt
+		}
+		filter = append(filter, bson.D{primitive.E{Key: "$or", Value: bson.A{
+			bson.D{primitive.E{Key: field, Value: bson.D{primitive.E{Key: cmp, Value: value}}}},
+			bson.D{{Key: field, Value: value}, {Key: "id", Value: bson.D{primitive.E{Key: cmp, Value: cur.ID}}}},
+		}}})
+	}
+	opts := options.Find().SetSort(bson.D{{Key: field, Value: order}, {Key: "id", Value: order}})
+	if query.Limit > 0 {
+		opts.SetLimit(int64(query.Limit + 1))
+	}
+	if cur == nil && query.Offset > 0 {
+		opts.SetSkip(int64(query.Offset))
+	}
+
+	cursor, err := collection.Find(ctx, mongoAnd(filter), opts)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	allcats := make([]*models.Cats, 0, query.Limit+1)
+	if err := cursor.All(ctx, &allcats); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	return newCatsPage(allcats, total, &query), nil
+}
+
+// mongoAnd joins filters of mongodb query by logical AND
+func mongoAnd(filters bson.A) bson.D {
+	if len(filters) == 0 {
+		return bson.D{}
+	}
+	return bson.D{primitive.E{Key: "$and", Value: filters}}
+}
+
+// cats returns collection of cats in mongodb
+func (c *MongoRepository) cats() *mongo.Collection {
+	return c.client.Database(c.cfg.MongoDBName).Collection(c.cfg.MongoCollection)
+}
+
+// CreateCat provides request to create cat in mongodb
+func (c *MongoRepository) CreateCat(ctx context.Context, cats models.Cats) (*models.Cats, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.MongoTimeout)
+	defer cancel()
+
+	cats.ID = uuid.New()
+	cats.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	cats.UpdatedAt = cats.CreatedAt
+	if _, err := c.cats().InsertOne(ctx, cats); err != nil {
+		log.Error(err)
+		return &cats, err
 	}
 	return &cats, nil
 }
 
 // GetCat provides request to get cat by 'id' from mongodb
-func (c *MongoRepository) GetCat(id uuid.UUID) (*models.Cats, error) {
-	var cat models.Cats
+func (c *MongoRepository) GetCat(ctx context.Context, id uuid.UUID) (*models.Cats, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.MongoTimeout)
+	defer cancel()
 
-	collection := c.client.Database(c.cfg.MongoDBName).Collection(c.cfg.MongoCollection)
-	err := collection.FindOne(context.TODO(), bson.D{primitive.E{Key: "id", Value: cat.ID}}).Decode(&cat)
+	var cat models.Cats
+	err := c.cats().FindOne(ctx, bson.D{primitive.E{Key: "id", Value: id}}).Decode(&cat)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrCatNotFound
+	}
 	if err != nil {
+		log.Error(err)
 		return nil, err
 	}
 	return &cat, nil
 }
 
-// UpdateCat provides request to update cat by 'id' in mongodb
-func (c *MongoRepository) UpdateCat(id uuid.UUID, cats models.Cats) (*models.Cats, error) {
-	collection := c.client.Database(c.cfg.MongoDBName).Collection(c.cfg.MongoCollection)
-	filter := bson.D{primitive.E{Key: "id", Value: cats.ID}}
-	update := bson.D{primitive.E{Key: "$set", Value: bson.D{primitive.E{Key: "name", Value: cats.Name}}}}
-	_, err := collection.UpdateOne(context.TODO(), filter, update)
+// UpdateCat provides request to update cat by 'id' in mongodb, owner and creation time aren't changed
+func (c *MongoRepository) UpdateCat(ctx context.Context, id uuid.UUID, cats models.Cats) (*models.Cats, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.MongoTimeout)
+	defer cancel()
+
+	update := bson.D{primitive.E{Key: "$set", Value: bson.D{
+		{Key: "name", Value: cats.Name},
+		{Key: "breed", Value: cats.Breed},
+		{Key: "birth_date", Value: cats.BirthDate},
+		{Key: "sex", Value: cats.Sex},
+		{Key: "color", Value: cats.Color},
+		{Key: "weight", Value: cats.Weight},
+		{Key: "status", Value: cats.Status},
+		{Key: "updated_at", Value: time.Now().UTC().Truncate(time.Millisecond)},
+	}}}
+	var cat models.Cats
+	err := c.cats().FindOneAndUpdate(ctx, bson.D{primitive.E{Key: "id", Value: id}}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&cat)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &cats, ErrCatNotFound
+	}
 	if err != nil {
-		log.Fatal(err)
+		log.Error(err)
+		return &cats, err
 	}
-	return &cats, nil
+	return &cat, nil
 }
 
 // DeleteCat provides request to delete cat by 'id' from mongodb
-func (c *MongoRepository) DeleteCat(id uuid.UUID) error {
-	collection := c.client.Database(c.cfg.MongoDBName).Collection(c.cfg.MongoCollection)
-	_, err := collection.DeleteOne(context.TODO(), bson.D{primitive.E{Key: "id", Value: id}})
+func (c *MongoRepository) DeleteCat(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.MongoTimeout)
+	defer cancel()
+
+	res, err := c.cats().DeleteOne(ctx, bson.D{primitive.E{Key: "id", Value: id}})
 	if err != nil {
-		log.Fatal(err)
+		log.Error("error while deleting a cat")
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrCatNotFound
 	}
 	return nil
 }
+
+// SearchCats provides search of cats by name in mongodb, matches of text index go first
+// and then names similar to query are looked for, when there are not enough of them
+func (c *MongoRepository) SearchCats(ctx context.Context, query string, limit int) ([]*models.Cats, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.MongoTimeout)
+	defer cancel()
+
+	collection := c.cats()
+	textScore := bson.D{primitive.E{Key: "$meta", Value: "textScore"}}
+	opts := options.Find().
+		SetProjection(bson.D{primitive.E{Key: "score", Value: textScore}}).
+		SetSort(bson.D{{Key: "score", Value: textScore}, {Key: "id", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := collection.Find(ctx, bson.D{primitive.E{Key: "$text", Value: bson.D{
+		primitive.E{Key: "$search", Value: query}}}}, opts)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	allcats := make([]*models.Cats, 0, limit)
+	if err := cursor.All(ctx, &allcats); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	if len(allcats) >= limit {
+		return allcats, nil
+	}
+
+	pattern := fuzzyPattern(query)
+	if pattern == "" {
+		return allcats, nil
+	}
+	found := make([]interface{}, 0, len(allcats))
+	for _, cat := range allcats {
+		found = append(found, cat.ID)
+	}
+	// only names of bounded number of cats looking alike are ranked, whole cats are loaded for best of them
+	cursor, err = collection.Find(ctx, bson.D{
+		{Key: "id", Value: bson.D{primitive.E{Key: "$nin", Value: found}}},
+		{Key: "name", Value: primitive.Regex{Pattern: pattern, Options: "i"}},
+	}, options.Find().
+		SetProjection(bson.D{{Key: "id", Value: 1}, {Key: "name", Value: 1}}).
+		SetLimit(fuzzyCandidates))
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	var candidates []*models.Cats
+	if err := cursor.All(ctx, &candidates); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	ranked := rankCats(candidates, query, limit-len(allcats))
+	if len(ranked) == 0 {
+		return allcats, nil
+	}
+	ids := make([]interface{}, 0, len(ranked))
+	for _, cat := range ranked {
+		ids = append(ids, cat.ID)
+	}
+	cursor, err = collection.Find(ctx, bson.D{primitive.E{Key: "id", Value: bson.D{
+		primitive.E{Key: "$in", Value: ids}}}})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	var matched []*models.Cats
+	if err := cursor.All(ctx, &matched); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*models.Cats, len(matched))
+	for _, cat := range matched {
+		byID[cat.ID] = cat
+	}
+	for _, cat := range ranked {
+		if full, ok := byID[cat.ID]; ok {
+			allcats = append(allcats, full)
+		}
+	}
+	return allcats, nil
+}
+
+// createCatsIndexes creates unique index on ids of cats, indexes for every order of listing
+// and text index on names, words are matched as is without stemming
+func (c *MongoRepository) createCatsIndexes(ctx context.Context) error {
+	_, err := c.cats().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{primitive.E{Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "name", Value: 1}, {Key: "id", Value: 1}}},
+		{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}}},
+		{Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "id", Value: 1}}},
+		{
+			Keys:    bson.D{primitive.E{Key: "name", Value: "text"}},
+			Options: options.Index().SetDefaultLanguage("none"),
+		},
+	})
+	return err
+}
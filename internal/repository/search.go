@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"CatsGo/internal/models"
+	"bytes"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// similarityThreshold is the minimal similarity of names treated as fuzzy match, same as pg_trgm default
+const similarityThreshold = 0.3
+
+// fuzzyCandidates limits number of names ranked in app by backends without trigram index
+const fuzzyCandidates = 500
+
+// searchWords splits s into lowercase words of letters and digits
+func searchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// trigrams returns set of trigrams of s the way pg_trgm does it:
+// every word is padded with two spaces in front and one at the end
+func trigrams(s string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, word := range searchWords(s) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = struct{}{}
+		}
+	}
+	return set
+}
+
+// similarity returns ratio of shared trigrams of a and b to all their trigrams, from 0 to 1
+func similarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range ta {
+		if _, ok := tb[t]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+// fuzzyPattern returns regexp of names worth ranking against query: names sharing a trigram
+// or first two letters of some word with it, words of query have no regexp metacharacters
+func fuzzyPattern(query string) string {
+	var parts []string
+	for _, word := range searchWords(query) {
+		runes := []rune(word)
+		if len(runes) < 3 {
+			parts = append(parts, `\b`+word)
+			continue
+		}
+		parts = append(parts, `\b`+string(runes[:2]))
+		for i := 0; i+3 <= len(runes); i++ {
+			parts = append(parts, string(runes[i:i+3]))
+		}
+	}
+	return strings.Join(parts, "|")
+}
+
+// containsWords reports whether every word of query is a word of name, like full-text match does
+func containsWords(name, query string) bool {
+	words := searchWords(query)
+	if len(words) == 0 {
+		return false
+	}
+	nameWords := make(map[string]struct{})
+	for _, word := range searchWords(name) {
+		nameWords[word] = struct{}{}
+	}
+	for _, word := range words {
+		if _, ok := nameWords[word]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// searchScore ranks name against query, full-text matches go before fuzzy ones,
+// zero score means name doesn't match at all
+func searchScore(name, query string) float64 {
+	score := similarity(name, query)
+	if containsWords(name, query) {
+		return score + 1
+	}
+	if score < similarityThreshold {
+		return 0
+	}
+	return score
+}
+
+// rankCats returns at most 'limit' cats matching query, best matches first
+func rankCats(cats []*models.Cats, query string, limit int) []*models.Cats {
+	type scored struct {
+		cat   *models.Cats
+		score float64
+	}
+	matches := make([]scored, 0, len(cats))
+	for _, cat := range cats {
+		if score := searchScore(cat.Name, query); score > 0 {
+			matches = append(matches, scored{cat: cat, score: score})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return bytes.Compare(matches[i].cat.ID[:], matches[j].cat.ID[:]) < 0
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	result := make([]*models.Cats, 0, len(matches))
+	for _, m := range matches {
+		result = append(result, m.cat)
+	}
+	return result
+}
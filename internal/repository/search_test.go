@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimilarity(t *testing.T) {
+	assert.InDelta(t, 1, similarity("Barsik", "barsik"), 1e-9)
+	// 4 shared trigrams of 10: "  b", " ba", "bar", "ars"
+	assert.InDelta(t, 0.4, similarity("Barsik", "Barsyk"), 1e-9)
+	assert.Zero(t, similarity("Barsik", ""))
+	assert.Less(t, similarity("Barsik", "Tom"), similarityThreshold)
+}
+
+func TestContainsWords(t *testing.T) {
+	assert.True(t, containsWords("Barsyk Junior", "junior"))
+	assert.True(t, containsWords("Barsyk Junior", "Junior, Barsyk"))
+	assert.False(t, containsWords("Barsyk Junior", "Barsyk Senior"))
+	assert.False(t, containsWords("Barsyk", "!!"))
+}
+
+func TestFuzzyPattern(t *testing.T) {
+	TestTable := []struct {
+		name        string
+		query       string
+		exceptMatch []string
+		exceptMiss  []string
+	}{
+		{
+			name:        "typo",
+			query:       "Barsyk",
+			exceptMatch: []string{"Barsik", "Old barsik"},
+			exceptMiss:  []string{"Tom", "Murka"},
+		},
+		{
+			name:        "short word",
+			query:       "Bo",
+			exceptMatch: []string{"Bob", "Big Bo"},
+			exceptMiss:  []string{"Tobo"},
+		},
+		{
+			name:        "several words",
+			query:       "Murzik Junior",
+			exceptMatch: []string{"Murzyk", "Junior"},
+			exceptMiss:  []string{"Tom"},
+		},
+	}
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			re := regexp.MustCompile("(?i)" + fuzzyPattern(TestCase.query))
+			for _, name := range TestCase.exceptMatch {
+				assert.True(t, re.MatchString(name), name)
+			}
+			for _, name := range TestCase.exceptMiss {
+				assert.False(t, re.MatchString(name), name)
+			}
+		})
+	}
+	assert.Empty(t, fuzzyPattern("!!"))
+}
@@ -0,0 +1,361 @@
+package repository
+
+import (
+	"CatsGo/internal/apperror"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTokenRevoked is returned when token or family of refresh token is expired or revoked
+	ErrTokenRevoked = apperror.New(apperror.Unauthorized, "token is revoked")
+	// ErrTokenReused is returned when refresh token was already used, its family is revoked then
+	ErrTokenReused = apperror.New(apperror.Unauthorized, "refresh token is reused")
+)
+
+// TokenStore keeps state of issued tokens: refresh tokens issued by rotation, every token family
+// starts on login and has the only valid refresh token at once, blacklist of access tokens,
+// versions of tokens of users and single-use tokens sent by email
+type TokenStore interface {
+	// StartFamily remembers 'jti' as the valid refresh token of new 'family'
+	StartFamily(ctx context.Context, family, jti string, ttl time.Duration) error
+	// RotateToken replaces valid refresh token 'jti' of 'family' with 'next', it returns
+	// ErrTokenReused and revokes family if 'jti' isn't valid anymore
+	RotateToken(ctx context.Context, family, jti, next string, ttl time.Duration) error
+	// RevokeFamily makes all refresh tokens of 'family' invalid
+	RevokeFamily(ctx context.Context, family string) error
+	// BlacklistToken makes access token 'jti' invalid for 'ttl', it's a time left till token expires
+	BlacklistToken(ctx context.Context, jti string, ttl time.Duration) error
+	IsBlacklisted(ctx context.Context, jti string) (bool, error)
+	// TokenVersion returns version of tokens of user, tokens of older versions are invalid
+	TokenVersion(ctx context.Context, userID uuid.UUID) (int64, error)
+	// BumpTokenVersion makes all tokens of user issued before invalid
+	BumpTokenVersion(ctx context.Context, userID uuid.UUID) (int64, error)
+	// SaveOneTimeToken remembers single-use token with SHA-256 'hash' issued to user for 'purpose'
+	SaveOneTimeToken(ctx context.Context, purpose, hash string, userID uuid.UUID, ttl time.Duration) error
+	// ConsumeOneTimeToken returns user of token and forgets it, it returns ErrTokenRevoked
+	// if token is unknown, used or expired
+	ConsumeOneTimeToken(ctx context.Context, purpose, hash string) (uuid.UUID, error)
+}
+
+// rotateScript swaps valid token of family, returns 0 for unknown family and -1 for reused token
+var rotateScript = redis.NewScript(`
+local current = redis.call("get", KEYS[1])
+if not current then
+	return 0
+end
+if current ~= ARGV[1] then
+	redis.call("del", KEYS[1])
+	return -1
+end
+redis.call("set", KEYS[1], ARGV[2], "PX", ARGV[3])
+return 1`)
+
+// consumeScript returns and deletes value of key atomically, GETDEL isn't supported by redis before 6.2
+var consumeScript = redis.NewScript(`
+local value = redis.call("get", KEYS[1])
+if value then
+	redis.call("del", KEYS[1])
+end
+return value`)
+
+// RedisTokenStore keeps state of tokens in redis
+type RedisTokenStore struct {
+	rdb     *redis.Client
+	timeout time.Duration
+}
+
+// NewRedisTokenStore is constructor
+func NewRedisTokenStore(rdb *redis.Client, timeout time.Duration) *RedisTokenStore {
+	return &RedisTokenStore{rdb: rdb, timeout: timeout}
+}
+
+func familyKey(family string) string {
+	return "rtfamily:" + family
+}
+
+func blacklistKey(jti string) string {
+	return "blacklist:" + jti
+}
+
+func versionKey(userID uuid.UUID) string {
+	return "tokenver:" + userID.String()
+}
+
+func oneTimeKey(purpose, hash string) string {
+	return "onetime:" + purpose + ":" + hash
+}
+
+// StartFamily saves 'jti' of new 'family' in redis
+func (s *RedisTokenStore) StartFamily(ctx context.Context, family, jti string, ttl time.Duration) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.rdb.Set(ctx, familyKey(family), jti, ttl).Err()
+}
+
+// RotateToken swaps valid token of 'family' in redis atomically
+func (s *RedisTokenStore) RotateToken(ctx context.Context, family, jti, next string, ttl time.Duration) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	res, err := rotateScript.Run(ctx, s.rdb, []string{familyKey(family)}, jti, next, ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	switch res {
+	case 0:
+		return ErrTokenRevoked
+	case -1:
+		return ErrTokenReused
+	}
+	return nil
+}
+
+// RevokeFamily deletes 'family' from redis
+func (s *RedisTokenStore) RevokeFamily(ctx context.Context, family string) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.rdb.Del(ctx, familyKey(family)).Err()
+}
+
+// BlacklistToken saves 'jti' in redis till token expires
+func (s *RedisTokenStore) BlacklistToken(ctx context.Context, jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		// token is already expired
+		return nil
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.rdb.Set(ctx, blacklistKey(jti), 1, ttl).Err()
+}
+
+// IsBlacklisted checks 'jti' in redis
+func (s *RedisTokenStore) IsBlacklisted(ctx context.Context, jti string) (bool, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	n, err := s.rdb.Exists(ctx, blacklistKey(jti)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// TokenVersion reads version of tokens of user from redis, it's 0 until user logs out everywhere
+func (s *RedisTokenStore) TokenVersion(ctx context.Context, userID uuid.UUID) (int64, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	version, err := s.rdb.Get(ctx, versionKey(userID)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return version, err
+}
+
+// BumpTokenVersion increments version of tokens of user in redis
+func (s *RedisTokenStore) BumpTokenVersion(ctx context.Context, userID uuid.UUID) (int64, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.rdb.Incr(ctx, versionKey(userID)).Result()
+}
+
+// SaveOneTimeToken saves user of single-use token in redis
+func (s *RedisTokenStore) SaveOneTimeToken(ctx context.Context, purpose, hash string, userID uuid.UUID,
+	ttl time.Duration) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.rdb.Set(ctx, oneTimeKey(purpose, hash), userID.String(), ttl).Err()
+}
+
+// ConsumeOneTimeToken reads and deletes single-use token from redis atomically
+func (s *RedisTokenStore) ConsumeOneTimeToken(ctx context.Context, purpose, hash string) (uuid.UUID, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	value, err := consumeScript.Run(ctx, s.rdb, []string{oneTimeKey(purpose, hash)}).Text()
+	if errors.Is(err, redis.Nil) {
+		return uuid.Nil, ErrTokenRevoked
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(value)
+}
+
+// memoryToken is a valid refresh token of family
+type memoryToken struct {
+	jti     string
+	expires time.Time
+}
+
+// memoryOneTimeToken is a user of single-use token
+type memoryOneTimeToken struct {
+	userID  uuid.UUID
+	expires time.Time
+}
+
+// MemoryTokenStore keeps tokens in memory, it's used with memory backend
+type MemoryTokenStore struct {
+	mu        sync.Mutex
+	families  map[string]memoryToken
+	blacklist map[string]time.Time
+	versions  map[uuid.UUID]int64
+	oneTime   map[string]memoryOneTimeToken
+}
+
+// NewMemoryTokenStore is constructor
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{
+		families:  make(map[string]memoryToken),
+		blacklist: make(map[string]time.Time),
+		versions:  make(map[uuid.UUID]int64),
+		oneTime:   make(map[string]memoryOneTimeToken),
+	}
+}
+
+// StartFamily saves 'jti' of new 'family'
+func (s *MemoryTokenStore) StartFamily(ctx context.Context, family, jti string, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.families[family] = memoryToken{jti: jti, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// RotateToken swaps valid token of 'family'
+func (s *MemoryTokenStore) RotateToken(ctx context.Context, family, jti, next string, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.families[family]
+	if !ok || time.Now().After(current.expires) {
+		delete(s.families, family)
+		return ErrTokenRevoked
+	}
+	if current.jti != jti {
+		delete(s.families, family)
+		return ErrTokenReused
+	}
+	s.families[family] = memoryToken{jti: next, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// RevokeFamily deletes 'family'
+func (s *MemoryTokenStore) RevokeFamily(ctx context.Context, family string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.families, family)
+	return nil
+}
+
+// BlacklistToken remembers 'jti' till token expires
+func (s *MemoryTokenStore) BlacklistToken(ctx context.Context, jti string, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for blacklisted, expires := range s.blacklist {
+		if now.After(expires) {
+			delete(s.blacklist, blacklisted)
+		}
+	}
+	if ttl > 0 {
+		s.blacklist[jti] = now.Add(ttl)
+	}
+	return nil
+}
+
+// IsBlacklisted checks 'jti'
+func (s *MemoryTokenStore) IsBlacklisted(ctx context.Context, jti string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expires, ok := s.blacklist[jti]
+	return ok && time.Now().Before(expires), nil
+}
+
+// TokenVersion returns version of tokens of user
+func (s *MemoryTokenStore) TokenVersion(ctx context.Context, userID uuid.UUID) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.versions[userID], nil
+}
+
+// BumpTokenVersion increments version of tokens of user
+func (s *MemoryTokenStore) BumpTokenVersion(ctx context.Context, userID uuid.UUID) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.versions[userID]++
+	return s.versions[userID], nil
+}
+
+// SaveOneTimeToken remembers user of single-use token
+func (s *MemoryTokenStore) SaveOneTimeToken(ctx context.Context, purpose, hash string, userID uuid.UUID,
+	ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, token := range s.oneTime {
+		if now.After(token.expires) {
+			delete(s.oneTime, key)
+		}
+	}
+	s.oneTime[oneTimeKey(purpose, hash)] = memoryOneTimeToken{userID: userID, expires: now.Add(ttl)}
+	return nil
+}
+
+// ConsumeOneTimeToken returns user of single-use token and forgets it
+func (s *MemoryTokenStore) ConsumeOneTimeToken(ctx context.Context, purpose, hash string) (uuid.UUID, error) {
+	if err := ctx.Err(); err != nil {
+		return uuid.Nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := oneTimeKey(purpose, hash)
+	token, ok := s.oneTime[key]
+	delete(s.oneTime, key)
+	if !ok || time.Now().After(token.expires) {
+		return uuid.Nil, ErrTokenRevoked
+	}
+	return token.userID, nil
+}
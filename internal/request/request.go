@@ -2,8 +2,11 @@
 package request
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 
@@ -13,11 +16,18 @@ import (
 // CustomValidator replace default Echo validator
 type CustomValidator struct {
 	Validator *validator.Validate
+	// Translator translates messages of ValidationError, they are in general words without it
+	Translator *ut.UniversalTranslator
 }
 
-// Validate func provides validation
+// Validate func provides validation, it returns ValidationError listing every invalid field
 func (c *CustomValidator) Validate(i interface{}) error {
-	if err := c.Validator.Struct(i); err != nil {
+	err := c.Validator.Struct(i)
+	var errs validator.ValidationErrors
+	if errors.As(err, &errs) {
+		return &ValidationError{errs: errs, trans: c.Translator}
+	}
+	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 	return nil
@@ -28,3 +38,59 @@ type CatID struct {
 	ID   uuid.UUID `json:"id" bson:"id"`
 	Name string    `json:"name" bson:"name" validate:"required,min=4"`
 }
+
+// CatsList contains query params of cats listing
+type CatsList struct {
+	Limit        int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Offset       int    `query:"offset" validate:"omitempty,min=0,excluded_with=Cursor"`
+	Cursor       string `query:"cursor"`
+	Sort         string `query:"sort" validate:"omitempty,oneof=name -name created_at -created_at"`
+	NamePrefix   string `query:"name_prefix" validate:"omitempty,max=120"`
+	NameContains string `query:"name" validate:"omitempty,max=120"`
+}
+
+// UserRole contains role assigned to user by admin
+type UserRole struct {
+	Role string `json:"role" validate:"required,oneof=admin staff viewer" enums:"admin,staff,viewer"`
+}
+
+// APIKeyCreate contains params of new API key
+type APIKeyCreate struct {
+	Name   string   `json:"name" validate:"required,max=60" example:"backup script"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=cats:read cats:write" enums:"cats:read,cats:write"`
+	// ExpiresAt is optional, keys without it never expire
+	ExpiresAt *time.Time `json:"expires_at,omitempty" validate:"omitempty,gt"`
+}
+
+// MFACode contains TOTP code from authenticator app or recovery code
+type MFACode struct {
+	Code string `json:"code" validate:"required,max=32" example:"123456"`
+}
+
+// MFALogin contains challenge token returned by login and TOTP or recovery code
+type MFALogin struct {
+	MFAToken string `json:"mfaToken" validate:"required,jwt"`
+	Code     string `json:"code" validate:"required,max=32" example:"123456"`
+}
+
+// PasswordForgot contains email of user who forgot password
+type PasswordForgot struct {
+	Email string `json:"email" validate:"required,email,max=254" example:"steve@example.com"`
+}
+
+// PasswordReset contains single-use token sent by email and new password
+type PasswordReset struct {
+	Token    string `json:"token" validate:"required,max=64"`
+	Password string `json:"password" validate:"required,max=20,min=6"`
+}
+
+// EmailVerify contains single-use token sent by email
+type EmailVerify struct {
+	Token string `json:"token" validate:"required,max=64"`
+}
+
+// CatsSearch contains query params of cats search
+type CatsSearch struct {
+	Query string `query:"q" validate:"required,min=2,max=120"`
+	Limit int    `query:"limit" validate:"omitempty,min=1,max=100"`
+}
@@ -0,0 +1,27 @@
+package request
+
+import (
+	"CatsGo/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomValidator_BirthDate(t *testing.T) {
+	v, err := NewCustomValidator()
+	require.NoError(t, err)
+
+	past := models.NewDate(time.Now().AddDate(-2, 0, 0))
+	assert.NoError(t, v.Validate(models.Cats{Name: "Barsik", BirthDate: &past}))
+
+	future := models.NewDate(time.Now().AddDate(0, 0, 2))
+	err = v.Validate(models.Cats{Name: "Barsik", BirthDate: &future})
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	fields := validationErr.Fields("en")
+	require.Len(t, fields, 1)
+	assert.Equal(t, "birth_date", fields[0].Field)
+	assert.Equal(t, "lte", fields[0].Rule)
+}
@@ -0,0 +1,121 @@
+package request
+
+import (
+	"CatsGo/internal/apperror"
+	"CatsGo/internal/models"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ru"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	entranslations "github.com/go-playground/validator/v10/translations/en"
+	rutranslations "github.com/go-playground/validator/v10/translations/ru"
+)
+
+// FieldError describes a failed rule of a single field of request
+type FieldError struct {
+	// Field is a name of field in JSON or query, items of arrays have index like scopes[0]
+	Field   string `json:"field" example:"name"`
+	Rule    string `json:"rule" example:"min"`
+	Param   string `json:"param,omitempty" example:"3"`
+	Message string `json:"message" example:"name must be at least 3 characters in length"`
+}
+
+// ValidationError is returned by CustomValidator when request has invalid fields,
+// messages are translated only when language of client is known
+type ValidationError struct {
+	errs  validator.ValidationErrors
+	trans *ut.UniversalTranslator
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.errs))
+	for _, fe := range e.errs {
+		fields = append(fields, fieldPath(fe)+": "+fe.Tag())
+	}
+	return "validation failed: " + strings.Join(fields, ", ")
+}
+
+// Unwrap makes ValidationError a validation error of app
+func (e *ValidationError) Unwrap() error {
+	return apperror.ErrValidation
+}
+
+// Fields returns failed fields with messages in the first supported of 'langs', English is used by default
+func (e *ValidationError) Fields(langs ...string) []FieldError {
+	var trans ut.Translator
+	if e.trans != nil {
+		trans, _ = e.trans.FindTranslator(langs...)
+	}
+	fields := make([]FieldError, 0, len(e.errs))
+	for _, fe := range e.errs {
+		// rules without translation are described in general words instead of internal message of validator
+		msg := fmt.Sprintf("%s doesn't satisfy rule %s", fe.Field(), fe.Tag())
+		if trans != nil {
+			if translated := fe.Translate(trans); translated != fe.Error() {
+				msg = translated
+			}
+		}
+		fields = append(fields, FieldError{Field: fieldPath(fe), Rule: fe.Tag(), Param: param(fe), Message: msg})
+	}
+	return fields
+}
+
+// paramNames are names clients use for fields which rules name in params, like excluded_with=Cursor
+var paramNames = map[string]string{
+	"Cursor": "cursor",
+}
+
+// param returns param of rule, Go name of field in it is replaced by name clients use
+func param(fe validator.FieldError) string {
+	if name, ok := paramNames[fe.Param()]; ok {
+		return name
+	}
+	return fe.Param()
+}
+
+// fieldPath returns path of field without name of validated struct
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
+// NewCustomValidator returns validator naming fields like clients do, by json or query tags,
+// its messages are translated to English and Russian
+func NewCustomValidator() (*CustomValidator, error) {
+	v := validator.New()
+	v.RegisterTagNameFunc(tagName)
+	// dates are checked by rules of time like 'lte'
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		return field.Interface().(models.Date).Time
+	}, models.Date{})
+
+	english := en.New()
+	trans := ut.New(english, english, ru.New())
+	enTrans, _ := trans.GetTranslator("en")
+	if err := entranslations.RegisterDefaultTranslations(v, enTrans); err != nil {
+		return nil, err
+	}
+	ruTrans, _ := trans.GetTranslator("ru")
+	if err := rutranslations.RegisterDefaultTranslations(v, ruTrans); err != nil {
+		return nil, err
+	}
+	return &CustomValidator{Validator: v, Translator: trans}, nil
+}
+
+// tagName returns name of field in json or query, fields skipped by json keep names of Go
+func tagName(field reflect.StructField) string {
+	for _, key := range []string{"json", "query"} {
+		name := strings.SplitN(field.Tag.Get(key), ",", 2)[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
@@ -0,0 +1,82 @@
+package request
+
+import (
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomValidator_Validate(t *testing.T) {
+	v, err := NewCustomValidator()
+	require.NoError(t, err)
+
+	err = v.Validate(APIKeyCreate{Name: "", Scopes: []string{"cats:read", "cats:eat"}})
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	TestTable := []struct {
+		name         string
+		inputLangs   []string
+		exceptFields []FieldError
+	}{
+		{
+			name:       "english",
+			inputLangs: []string{"en"},
+			exceptFields: []FieldError{
+				{Field: "name", Rule: "required", Message: "name is a required field"},
+				{Field: "scopes[1]", Rule: "oneof", Param: "cats:read cats:write",
+					Message: "scopes[1] must be one of [cats:read cats:write]"},
+			},
+		},
+		{
+			name:       "russian",
+			inputLangs: []string{"ru", "en"},
+			exceptFields: []FieldError{
+				{Field: "name", Rule: "required", Message: "name обязательное поле"},
+				{Field: "scopes[1]", Rule: "oneof", Param: "cats:read cats:write",
+					Message: "scopes[1] должен быть одним из [cats:read cats:write]"},
+			},
+		},
+		{
+			name:       "unknown language falls back to english",
+			inputLangs: []string{"de"},
+			exceptFields: []FieldError{
+				{Field: "name", Rule: "required", Message: "name is a required field"},
+				{Field: "scopes[1]", Rule: "oneof", Param: "cats:read cats:write",
+					Message: "scopes[1] must be one of [cats:read cats:write]"},
+			},
+		},
+	}
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			assert.Equal(t, TestCase.exceptFields, validationErr.Fields(TestCase.inputLangs...))
+		})
+	}
+}
+
+func TestCustomValidator_QueryAndUntranslated(t *testing.T) {
+	v, err := NewCustomValidator()
+	require.NoError(t, err)
+
+	err = v.Validate(CatsSearch{Query: "a"})
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	fields := validationErr.Fields("en")
+	require.Len(t, fields, 1)
+	assert.Equal(t, "q", fields[0].Field, "fields of query are named by query tag")
+
+	// rule excluded_with has no translation
+	err = v.Validate(CatsList{Offset: 20, Cursor: "cursor"})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []FieldError{{Field: "offset", Rule: "excluded_with", Param: "cursor",
+		Message: "offset doesn't satisfy rule excluded_with"}}, validationErr.Fields("en"))
+
+	// validator without translator describes rules in general words
+	plain := &CustomValidator{Validator: validator.New()}
+	err = plain.Validate(CatsSearch{})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "Query doesn't satisfy rule required", validationErr.Fields("en")[0].Message)
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"CatsGo/internal/apperror"
+	"CatsGo/internal/mailer"
+	"CatsGo/internal/models"
+	"CatsGo/internal/repository"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+)
+
+// Purposes of single-use tokens sent by email
+const (
+	purposePasswordReset     = "password_reset"
+	purposeEmailVerification = "email_verification"
+	oneTimeTokenBytes        = 32
+	// backgroundTimeout limits work continued after response is sent
+	backgroundTimeout = time.Minute
+)
+
+var (
+	// ErrEmailVerified is returned when verification is requested for verified email
+	ErrEmailVerified = apperror.New(apperror.Conflict, "email is already verified")
+	// ErrNoEmail is returned when user registered before emails has none
+	ErrNoEmail = apperror.New(apperror.Conflict, "user has no email")
+)
+
+// ForgotPasswordServ sends link for password reset to 'email' in background, nothing is sent to unknown email.
+// Neither result nor duration of call tells whether email is known, so failures are only logged
+func (s *UserAuthService) ForgotPasswordServ(email string) {
+	s.sendInBackground("password reset", func(ctx context.Context) error {
+		return s.sendPasswordReset(ctx, email)
+	})
+}
+
+// sendInBackground runs 'send' after response without context of request, emails over MailConcurrency
+// are dropped so that requests can't pile up senders, failures are only logged
+func (s *UserAuthService) sendInBackground(what string, send func(ctx context.Context) error) {
+	select {
+	case s.mailSlots <- struct{}{}:
+	default:
+		log.Errorf("%s isn't sent, too many emails are being sent", what)
+		return
+	}
+	s.background.Add(1)
+	go func() {
+		defer func() {
+			<-s.mailSlots
+			s.background.Done()
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), backgroundTimeout)
+		defer cancel()
+		if err := send(ctx); err != nil {
+			log.Errorf("error while sending %s: %v", what, err)
+		}
+	}()
+}
+
+// sendPasswordReset sends link for password reset to user with 'email' if there is one
+func (s *UserAuthService) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := s.repository.GetUserByEmail(ctx, email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := s.issueOneTimeToken(ctx, purposePasswordReset, user.ID, s.cfg.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi, %s!\n\nSomeone asked to reset password of your account %s. "+
+			"If it was you, follow the link:\n%s\n\nor use token %s. It is valid for %s.\n"+
+			"Otherwise just ignore this email.\n",
+			user.Name, user.Username, s.link("/password/reset", token), token, s.cfg.PasswordResetTTL),
+	})
+}
+
+// ResetPasswordServ replaces password of user who got single-use 'token' by ForgotPasswordServ,
+// all tokens of user issued before are revoked, unknown 'token' is a validation error
+func (s *UserAuthService) ResetPasswordServ(ctx context.Context, token, password string) error {
+	userID, err := s.tokens.ConsumeOneTimeToken(ctx, purposePasswordReset, hashSecret(token))
+	if errors.Is(err, repository.ErrTokenRevoked) {
+		return apperror.Wrap(apperror.Validation, ErrInvalidToken)
+	}
+	if err != nil {
+		return err
+	}
+
+	hash, err := hashPassword(password, s.cfg)
+	if err != nil {
+		log.Error("error while hashing password")
+		return err
+	}
+	if err := s.repository.UpdatePassword(ctx, userID, hash); err != nil {
+		return err
+	}
+	if _, err := s.tokens.BumpTokenVersion(ctx, userID); err != nil {
+		log.Error("error while bumping version of tokens")
+		return err
+	}
+	log.Infof("audit: password of user %s is reset", userID)
+	return nil
+}
+
+// SendVerificationServ sends link for verification of email to user
+func (s *UserAuthService) SendVerificationServ(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.repository.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return ErrNoEmail
+	}
+	if user.EmailVerified {
+		return ErrEmailVerified
+	}
+	return s.sendVerification(ctx, user)
+}
+
+// VerifyEmailServ marks email of user who got single-use 'token' as verified
+func (s *UserAuthService) VerifyEmailServ(ctx context.Context, token string) error {
+	userID, err := s.tokens.ConsumeOneTimeToken(ctx, purposeEmailVerification, hashSecret(token))
+	if errors.Is(err, repository.ErrTokenRevoked) {
+		return apperror.Wrap(apperror.Validation, ErrInvalidToken)
+	}
+	if err != nil {
+		return err
+	}
+	return s.repository.SetEmailVerified(ctx, userID)
+}
+
+// sendVerification sends link for verification of email of new user
+func (s *UserAuthService) sendVerification(ctx context.Context, user models.User) error {
+	token, err := s.issueOneTimeToken(ctx, purposeEmailVerification, user.ID, s.cfg.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf("Hi, %s!\n\nConfirm email of your account %s by following the link:\n%s\n\n"+
+			"or use token %s. It is valid for %s.\n",
+			user.Name, user.Username, s.link("/email/verify", token), token, s.cfg.EmailVerificationTTL),
+	})
+}
+
+// issueOneTimeToken generates random single-use token of user, only hash of it is saved
+func (s *UserAuthService) issueOneTimeToken(ctx context.Context, purpose string, userID uuid.UUID,
+	ttl time.Duration) (string, error) {
+	random := make([]byte, oneTimeTokenBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(random)
+	if err := s.tokens.SaveOneTimeToken(ctx, purpose, hashSecret(token), userID, ttl); err != nil {
+		log.Error("error while saving single-use token")
+		return "", err
+	}
+	return token, nil
+}
+
+// link returns URL of app with 'path' and 'token' in query
+func (s *UserAuthService) link(path, token string) string {
+	return s.cfg.AppBaseURL + path + "?" + url.Values{"token": {token}}.Encode()
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"CatsGo/internal/mailer"
+	"CatsGo/internal/models"
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sentToken returns token from link in the last email sent by service
+func sentToken(t *testing.T, s *UserAuthService) string {
+	sent := s.mailer.(*recordingMailer).sent
+	require.NotEmpty(t, sent)
+	body := sent[len(sent)-1].Body
+	start := strings.Index(body, s.cfg.AppBaseURL)
+	require.NotEqual(t, -1, start, "email contains link")
+	link, err := url.Parse(strings.Fields(body[start:])[0])
+	require.NoError(t, err)
+	return link.Query().Get("token")
+}
+
+// newEmailAuthService returns service with user 'steve' who has email
+func newEmailAuthService(t *testing.T) (*UserAuthService, models.User) {
+	s, _ := newTestAuthService(t)
+	s.cfg.AppBaseURL, s.cfg.PasswordResetTTL, s.cfg.EmailVerificationTTL = "http://cats.test", time.Hour, time.Hour
+	user, err := s.CreateUserServ(context.Background(), models.User{
+		Name: "Steve Wozniak", Username: "woz", Password: "Stev13_jb7", Email: "Woz@example.com", EmailVerified: true,
+	})
+	require.NoError(t, err)
+	// verification is sent in background
+	s.Close()
+	return s, user
+}
+
+func TestUserAuthService_VerifyEmailServ(t *testing.T) {
+	ctx := context.Background()
+	s, user := newEmailAuthService(t)
+	assert.False(t, user.EmailVerified, "email can't be verified on sign up")
+	sent := s.mailer.(*recordingMailer).sent
+	require.Len(t, sent, 1, "verification is sent on sign up")
+	assert.Equal(t, "Woz@example.com", sent[0].To)
+	token := sentToken(t, s)
+
+	assert.ErrorIs(t, s.VerifyEmailServ(ctx, "unknown"), ErrInvalidToken)
+	require.NoError(t, s.VerifyEmailServ(ctx, token))
+	assert.ErrorIs(t, s.VerifyEmailServ(ctx, token), ErrInvalidToken, "token can be used once")
+
+	verified, err := s.repository.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, verified.EmailVerified)
+	assert.ErrorIs(t, s.SendVerificationServ(ctx, user.ID), ErrEmailVerified)
+}
+
+func TestUserAuthService_SendVerificationServ(t *testing.T) {
+	ctx := context.Background()
+	s, user := newEmailAuthService(t)
+	first := sentToken(t, s)
+
+	require.NoError(t, s.SendVerificationServ(ctx, user.ID))
+	second := sentToken(t, s)
+	assert.NotEqual(t, first, second)
+	require.NoError(t, s.VerifyEmailServ(ctx, second))
+
+	withoutEmail, steve := newTestAuthService(t)
+	assert.ErrorIs(t, withoutEmail.SendVerificationServ(ctx, steve.ID), ErrNoEmail)
+}
+
+func TestUserAuthService_ResetPasswordServ(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newEmailAuthService(t)
+	access, _, _, err := s.GenerateToken(ctx, "woz", "Stev13_jb7", "")
+	require.NoError(t, err)
+
+	sent := len(s.mailer.(*recordingMailer).sent)
+	s.ForgotPasswordServ("nobody@example.com")
+	s.Close()
+	assert.Len(t, s.mailer.(*recordingMailer).sent, sent, "nothing is sent to unknown email")
+
+	s.ForgotPasswordServ("woz@EXAMPLE.com")
+	s.Close()
+	token := sentToken(t, s)
+	assert.ErrorIs(t, s.ResetPasswordServ(ctx, "unknown", "n3w_pass"), ErrInvalidToken)
+	require.NoError(t, s.ResetPasswordServ(ctx, token, "n3w_pass"))
+	assert.ErrorIs(t, s.ResetPasswordServ(ctx, token, "an0ther"), ErrInvalidToken, "token can be used once")
+
+	_, _, _, err = s.GenerateToken(ctx, "woz", "Stev13_jb7", "")
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "old password is replaced")
+	_, _, _, err = s.GenerateToken(ctx, "woz", "n3w_pass", "")
+	assert.NoError(t, err)
+	assert.Error(t, s.CheckToken(ctx, tokenClaims(t, s, access)), "sessions are revoked")
+}
+
+// blockingMailer counts emails and fails to send them once 'release' is closed
+type blockingMailer struct {
+	release chan struct{}
+	calls   int32
+}
+
+func (m *blockingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	atomic.AddInt32(&m.calls, 1)
+	<-m.release
+	return errors.New("smtp server is down")
+}
+
+func TestUserAuthService_ForgotPasswordServ_Background(t *testing.T) {
+	s, _ := newEmailAuthService(t)
+	mail := &blockingMailer{release: make(chan struct{})}
+	s.mailer = mail
+
+	returned := make(chan struct{})
+	go func() {
+		s.ForgotPasswordServ("woz@example.com")
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("request waits for email to be sent")
+	}
+	close(mail.release)
+	s.Close()
+}
+
+func TestUserAuthService_ForgotPasswordServ_Concurrency(t *testing.T) {
+	s, _ := newEmailAuthService(t)
+	mail := &blockingMailer{release: make(chan struct{})}
+	s.mailer = mail
+
+	// limit of test config is a single email at once, the second one is dropped while the first is sent
+	s.ForgotPasswordServ("woz@example.com")
+	s.ForgotPasswordServ("woz@example.com")
+	close(mail.release)
+	s.Close()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&mail.calls))
+
+	mail.release = make(chan struct{})
+	close(mail.release)
+	s.ForgotPasswordServ("woz@example.com")
+	s.Close()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&mail.calls), "slot is free once email is sent")
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"CatsGo/internal/apperror"
+	"CatsGo/internal/models"
+	"CatsGo/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	// apiKeyPrefix marks API keys so that they are easy to find in leaked secrets
+	apiKeyPrefix = "cgk_"
+	apiKeyBytes  = 32
+	// apiKeyShownLen is a length of beginning of key kept to tell keys apart
+	apiKeyShownLen = len(apiKeyPrefix) + 4
+)
+
+// ErrInvalidAPIKey is returned when API key is unknown or expired
+var ErrInvalidAPIKey = apperror.New(apperror.Unauthorized, "invalid api key")
+
+// hashSecret returns hex of SHA-256 of random secret like API key, such secrets don't need salt
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKeyServ generates new API key of user, the key is returned only here
+func (s *UserAuthService) CreateAPIKeyServ(ctx context.Context, userID uuid.UUID, name string, scopes []string,
+	expiresAt *time.Time) (key string, apiKey models.APIKey, err error) {
+	random := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", models.APIKey{}, err
+	}
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(random)
+
+	apiKey, err = s.repository.CreateAPIKey(ctx, models.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    key[:apiKeyShownLen],
+		Hash:      hashSecret(key),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		log.Error("error while saving api key")
+		return "", models.APIKey{}, err
+	}
+	return key, apiKey, nil
+}
+
+// ListAPIKeysServ returns API keys of user without keys themselves
+func (s *UserAuthService) ListAPIKeysServ(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	return s.repository.ListAPIKeys(ctx, userID)
+}
+
+// RevokeAPIKeyServ deletes API key of user
+func (s *UserAuthService) RevokeAPIKeyServ(ctx context.Context, userID, id uuid.UUID) error {
+	return s.repository.DeleteAPIKey(ctx, userID, id)
+}
+
+// AuthenticateAPIKey returns claims of user who owns API key, they are limited by scopes of key
+func (s *UserAuthService) AuthenticateAPIKey(ctx context.Context, key string) (*JwtCustomClaims, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	apiKey, err := s.repository.GetAPIKeyByHash(ctx, hashSecret(key))
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.ExpiresAt != nil && time.Now().After(*apiKey.ExpiresAt) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.repository.GetUserByID(ctx, apiKey.UserID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &JwtCustomClaims{
+		ID:     user.ID,
+		Name:   user.Username,
+		Role:   user.Role,
+		Type:   TokenTypeAPIKey,
+		Scopes: apiKey.Scopes,
+	}, nil
+}
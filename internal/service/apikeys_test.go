@@ -0,0 +1,66 @@
+package service
+
+import (
+	"CatsGo/internal/models"
+	"CatsGo/internal/repository"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserAuthService_APIKeys(t *testing.T) {
+	ctx := context.Background()
+	s, user := newTestAuthService(t)
+
+	key, apiKey, err := s.CreateAPIKeyServ(ctx, user.ID, "backup", []string{models.ScopeCatsRead}, nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, apiKeyPrefix))
+	assert.Equal(t, key[:apiKeyShownLen], apiKey.Prefix)
+	assert.NotContains(t, apiKey.Hash, key, "key isn't stored as is")
+
+	claims, err := s.AuthenticateAPIKey(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.ID)
+	assert.Equal(t, TokenTypeAPIKey, claims.Type)
+	assert.Equal(t, []string{models.ScopeCatsRead}, claims.Scopes)
+
+	keys, err := s.ListAPIKeysServ(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, apiKey.ID, keys[0].ID)
+
+	assert.ErrorIs(t, s.RevokeAPIKeyServ(ctx, uuid.New(), apiKey.ID), repository.ErrAPIKeyNotFound,
+		"keys of other users can't be revoked")
+	require.NoError(t, s.RevokeAPIKeyServ(ctx, user.ID, apiKey.ID))
+	_, err = s.AuthenticateAPIKey(ctx, key)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestUserAuthService_AuthenticateAPIKey_Invalid(t *testing.T) {
+	ctx := context.Background()
+	s, user := newTestAuthService(t)
+	expired := time.Now().Add(-time.Minute)
+	expiredKey, _, err := s.CreateAPIKeyServ(ctx, user.ID, "old", []string{models.ScopeCatsRead}, &expired)
+	require.NoError(t, err)
+
+	TestTable := []struct {
+		name string
+		key  string
+	}{
+		{name: "expired", key: expiredKey},
+		{name: "unknown", key: apiKeyPrefix + "unknown"},
+		{name: "wrong prefix", key: "key_" + strings.TrimPrefix(expiredKey, apiKeyPrefix)},
+		{name: "empty", key: ""},
+	}
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			_, err := s.AuthenticateAPIKey(ctx, TestCase.key)
+			assert.ErrorIs(t, err, ErrInvalidAPIKey)
+		})
+	}
+}
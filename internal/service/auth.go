@@ -2,11 +2,14 @@
 package service
 
 import (
+	"CatsGo/internal/apperror"
 	"CatsGo/internal/configs"
+	"CatsGo/internal/mailer"
 	"CatsGo/internal/models"
 	"CatsGo/internal/repository"
-	"crypto/sha256"
-	"fmt"
+	"context"
+	"errors"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt"
@@ -21,138 +24,371 @@ const (
 	nrtt = 3  // new refresh token time
 )
 
+// Types of tokens kept in 'typ' claim, API keys are never signed and get claims on authentication
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	TokenTypeAPIKey  = "api_key"
+	// TokenTypeMFA is a challenge token exchanged for access and refresh tokens by TOTP code
+	TokenTypeMFA = "mfa"
+)
+
+// ErrInvalidToken is returned when token isn't valid or has wrong type
+var ErrInvalidToken = apperror.New(apperror.Unauthorized, "invalid token")
+
 // UserAuthService implements an interface of Auth from repository
 type UserAuthService struct {
 	repository repository.Auth
+	tokens     repository.TokenStore
+	attempts   repository.LoginAttempts
+	mailer     mailer.Mailer
+	keys       *KeySet
 	cfg        *configs.Config
+
+	// dummyHash is verified when user is missing so that login takes the same time
+	dummyHash string
+	// background counts emails being sent after response, mailSlots limits how many of them are sent at once
+	background sync.WaitGroup
+	mailSlots  chan struct{}
 }
 
 // Auth contains methods for auth cases
 type Auth interface {
-	CreateUserServ(user models.User) (models.User, error)
-	GenerateToken(username, password string) (t, rt string, err error)
-	RefreshTokens(rt string) (nt, nrt string, err error)
+	CreateUserServ(ctx context.Context, user models.User) (models.User, error)
+	GenerateToken(ctx context.Context, username, password, ip string) (t, rt, mfaToken string, err error)
+	LoginMFA(ctx context.Context, mfaToken, code string) (t, rt string, err error)
+	RefreshTokens(ctx context.Context, rt string) (nt, nrt string, err error)
+	Logout(ctx context.Context, claims *JwtCustomClaims, rt string) error
+	LogoutAll(ctx context.Context, claims *JwtCustomClaims) error
+	CheckToken(ctx context.Context, claims *JwtCustomClaims) error
+	JWKS() JSONWebKeySet
+	SetUserRoleServ(ctx context.Context, id uuid.UUID, role string) (models.User, error)
+	CreateAPIKeyServ(ctx context.Context, userID uuid.UUID, name string, scopes []string,
+		expiresAt *time.Time) (key string, apiKey models.APIKey, err error)
+	ListAPIKeysServ(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error)
+	RevokeAPIKeyServ(ctx context.Context, userID, id uuid.UUID) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*JwtCustomClaims, error)
+	EnrollTOTPServ(ctx context.Context, userID uuid.UUID) (secret, uri string, err error)
+	ConfirmTOTPServ(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	DisableTOTPServ(ctx context.Context, userID uuid.UUID, code string) error
+	ForgotPasswordServ(email string)
+	ResetPasswordServ(ctx context.Context, token, password string) error
+	SendVerificationServ(ctx context.Context, userID uuid.UUID) error
+	VerifyEmailServ(ctx context.Context, token string) error
 }
 
 // NewUserAuthService is a constructor
-func NewUserAuthService(r repository.Auth, cfg *configs.Config) *UserAuthService {
-	return &UserAuthService{repository: r, cfg: cfg}
+func NewUserAuthService(r repository.Auth, tokens repository.TokenStore, attempts repository.LoginAttempts,
+	mail mailer.Mailer, keys *KeySet, cfg *configs.Config) (*UserAuthService, error) {
+	dummyHash, err := hashPassword("dummy password", cfg)
+	if err != nil {
+		log.Error("error while hashing dummy password")
+		return nil, err
+	}
+	// at least one email is sent at once even if limit isn't configured
+	mailConcurrency := cfg.MailConcurrency
+	if mailConcurrency <= 0 {
+		mailConcurrency = 1
+	}
+	return &UserAuthService{repository: r, tokens: tokens, attempts: attempts, mailer: mail, keys: keys, cfg: cfg,
+		dummyHash: dummyHash, mailSlots: make(chan struct{}, mailConcurrency)}, nil
+}
+
+// Close waits for emails being sent in background, it's called once server doesn't take requests
+func (s *UserAuthService) Close() {
+	s.background.Wait()
 }
 
 // JwtCustomClaims expands the jwt.StandardClaims
 type JwtCustomClaims struct {
 	ID   uuid.UUID `json:"id"`
 	Name string    `json:"name"`
+	// Type is TokenTypeAccess, TokenTypeRefresh, TokenTypeMFA or TokenTypeAPIKey
+	Type string `json:"typ"`
+	// Family is an id of chain of refresh tokens started on login
+	Family string `json:"fam,omitempty"`
+	// Version is a version of tokens of user, it's bumped when user logs out everywhere
+	Version int64  `json:"ver"`
+	Role    string `json:"role"`
+	// Scopes limit requests made with API keys, tokens without scopes aren't limited
+	Scopes []string `json:"scopes,omitempty"`
 	jwt.StandardClaims
 }
 
-// CreateUserServ provides new service for user
-func (s *UserAuthService) CreateUserServ(user models.User) (models.User, error) {
-	user.Password = generatePassword(user.Password, s.cfg)
-	return s.repository.CreateUser(user)
+// CreateUserServ provides new service for user, link for verification of email is sent to new user
+func (s *UserAuthService) CreateUserServ(ctx context.Context, user models.User) (models.User, error) {
+	hash, err := hashPassword(user.Password, s.cfg)
+	if err != nil {
+		log.Error("error while hashing password")
+		return models.User{}, err
+	}
+	user.Password = hash
+	user.Role = models.RoleViewer
+	if s.cfg.AdminUsername != "" && user.Username == s.cfg.AdminUsername {
+		user.Role = models.RoleAdmin
+	}
+	user.EmailVerified = false
+	created, err := s.repository.CreateUser(ctx, user)
+	if err != nil {
+		return models.User{}, err
+	}
+	if created.Email != "" {
+		// user can ask for the link again, so registration neither waits for email nor fails with it
+		s.sendInBackground("verification of email", func(ctx context.Context) error {
+			return s.sendVerification(ctx, created)
+		})
+	}
+	return created, nil
 }
 
-// GenerateToken func creates a pair of jwt tokens
-func (s *UserAuthService) GenerateToken(username, password string) (t, rt string, err error) {
-	user, err := s.repository.GetUser(username, generatePassword(password, s.cfg))
+// GenerateToken func creates a pair of jwt tokens and starts new family of refresh tokens, when user has
+// TOTP enabled only challenge 'mfaToken' is returned and tokens are issued by LoginMFA. Failed logins
+// of user and from 'ip' are throttled, unknown username and incorrect password give ErrInvalidCredentials
+func (s *UserAuthService) GenerateToken(ctx context.Context, username, password, ip string) (t, rt, mfaToken string, err error) {
+	limits := s.loginLimits(username, ip)
+	if err := s.checkLoginAllowed(ctx, limits); err != nil {
+		return "", "", "", err
+	}
+	user, rehash, err := s.checkPassword(ctx, username, password)
+	if errors.Is(err, ErrInvalidCredentials) {
+		if err := s.loginFailed(ctx, limits); err != nil {
+			return "", "", "", err
+		}
+		return "", "", "", ErrInvalidCredentials
+	}
 	if err != nil {
-		log.Error("error with generate token in repository")
-		return "", "", err
+		return "", "", "", err
+	}
+	s.loginSucceeded(ctx, limits)
+	if rehash {
+		s.rehashPassword(ctx, user, password)
 	}
 
-	ac := &JwtCustomClaims{
-		ID:   user.ID,
-		Name: user.Username,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Minute * att).Unix(),
-		},
+	version, err := s.tokens.TokenVersion(ctx, user.ID)
+	if err != nil {
+		log.Error("error while reading version of tokens")
+		return "", "", "", err
+	}
+	if user.TOTPEnabled {
+		mfaToken, err = s.signMFAToken(user, version)
+		if err != nil {
+			log.Error("error during generate mfa token")
+			return "", "", "", err
+		}
+		return "", "", mfaToken, nil
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, ac)
+	t, rt, err = s.issueTokens(ctx, user, version)
+	return t, rt, "", err
+}
 
-	// Generate encoded token and send it as response.
-	t, err = token.SignedString([]byte(s.cfg.KeyForSignatureJwt))
+// checkPassword returns user if 'password' matches, hash is verified even for unknown 'username'
+// so that it can't be told from incorrect password by time of response
+func (s *UserAuthService) checkPassword(ctx context.Context, username, password string) (user models.User, rehash bool, err error) {
+	user, err = s.repository.GetUser(ctx, username)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		_, _ = verifyPassword(password, s.dummyHash, s.cfg)
+		return models.User{}, false, ErrInvalidCredentials
+	}
 	if err != nil {
-		log.Error("error during generate token")
+		log.Error("error with generate token in repository")
+		return models.User{}, false, err
+	}
+	rehash, err = verifyPassword(password, user.Password, s.cfg)
+	if errors.Is(err, ErrIncorrectPassword) {
+		return models.User{}, false, ErrInvalidCredentials
+	}
+	if err != nil {
+		return models.User{}, false, err
+	}
+	return user, rehash, nil
+}
+
+// issueTokens creates a pair of jwt tokens of user and starts new family of refresh tokens
+func (s *UserAuthService) issueTokens(ctx context.Context, user models.User, version int64) (t, rt string, err error) {
+	claims := JwtCustomClaims{ID: user.ID, Name: user.Username, Family: uuid.NewString(), Version: version, Role: user.Role}
+	claims.StandardClaims.Id = uuid.NewString()
+	if err := s.tokens.StartFamily(ctx, claims.Family, claims.StandardClaims.Id, time.Hour*rtt); err != nil {
+		log.Error("error while saving refresh token")
 		return "", "", err
 	}
+	return s.signTokens(claims, time.Minute*att, time.Hour*rtt)
+}
 
-	rfc := &JwtCustomClaims{
-		ID: user.ID,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour * rtt).Unix(),
-		},
+// RefreshTokens func exchanges refresh token for a new pair of tokens, every refresh token
+// can be used once, reuse of it revokes all refresh tokens of its family
+func (s *UserAuthService) RefreshTokens(ctx context.Context, rt string) (nt, nrt string, err error) {
+	verifyResult, err := VerifyToken(rt, s.keys)
+	if err != nil {
+		log.Error("token not verified")
+		return "", "", ErrInvalidToken
+	}
+	claims := verifyResult.Claims.(*JwtCustomClaims)
+	if claims.Type != TokenTypeRefresh || claims.Family == "" {
+		return "", "", ErrInvalidToken
+	}
+	version, err := s.tokens.TokenVersion(ctx, claims.ID)
+	if err != nil {
+		log.Error("error while reading version of tokens")
+		return "", "", err
+	}
+	if claims.Version < version {
+		return "", "", repository.ErrTokenRevoked
 	}
-	refToken := jwt.NewWithClaims(jwt.SigningMethodHS256, rfc)
 
-	rt, err = refToken.SignedString([]byte(s.cfg.KeyForSignatureJwt))
+	next := *claims
+	next.StandardClaims.Id = uuid.NewString()
+	err = s.tokens.RotateToken(ctx, claims.Family, claims.StandardClaims.Id, next.StandardClaims.Id, time.Hour*nrtt)
+	if errors.Is(err, repository.ErrTokenReused) {
+		log.Warnf("refresh token of user %s is reused, family %s is revoked", claims.ID, claims.Family)
+	}
 	if err != nil {
-		log.Error("error during generate refresh token")
 		return "", "", err
 	}
+	return s.signTokens(next, time.Minute*natt, time.Hour*nrtt)
+}
 
-	return t, rt, nil
+// Logout revokes family of refresh token 'rt' and blacklists access token of 'claims' till it expires,
+// invalid 'rt' is a validation error since request itself is authenticated
+func (s *UserAuthService) Logout(ctx context.Context, claims *JwtCustomClaims, rt string) error {
+	verifyResult, err := VerifyToken(rt, s.keys)
+	if err != nil {
+		return apperror.Wrap(apperror.Validation, ErrInvalidToken)
+	}
+	refresh := verifyResult.Claims.(*JwtCustomClaims)
+	if refresh.Type != TokenTypeRefresh || refresh.ID != claims.ID {
+		return apperror.Wrap(apperror.Validation, ErrInvalidToken)
+	}
+
+	if err := s.tokens.RevokeFamily(ctx, refresh.Family); err != nil {
+		log.Error("error while revoking refresh token")
+		return err
+	}
+	ttl := time.Until(time.Unix(claims.ExpiresAt, 0))
+	if err := s.tokens.BlacklistToken(ctx, claims.StandardClaims.Id, ttl); err != nil {
+		log.Error("error while blacklisting access token")
+		return err
+	}
+	return nil
 }
 
-// RefreshTokens func provides force update a pair of tokens
-func (s *UserAuthService) RefreshTokens(rt string) (nt, nrt string, err error) {
-	verifyResult, err := VerifyToken(rt, s.cfg)
+// LogoutAll revokes all tokens of user issued before
+func (s *UserAuthService) LogoutAll(ctx context.Context, claims *JwtCustomClaims) error {
+	if _, err := s.tokens.BumpTokenVersion(ctx, claims.ID); err != nil {
+		log.Error("error while bumping version of tokens")
+		return err
+	}
+	return nil
+}
 
-	if verifyResult == nil {
-		log.Error("token not verified")
-		return "", "", err
+// CheckToken returns repository.ErrTokenRevoked if access token of 'claims' is revoked,
+// API keys are checked on authentication
+func (s *UserAuthService) CheckToken(ctx context.Context, claims *JwtCustomClaims) error {
+	if claims.Type == TokenTypeAPIKey {
+		return nil
+	}
+	blacklisted, err := s.tokens.IsBlacklisted(ctx, claims.StandardClaims.Id)
+	if err != nil {
+		log.Error("error while checking blacklist of tokens")
+		return err
+	}
+	if blacklisted {
+		return repository.ErrTokenRevoked
 	}
-	ncl := &JwtCustomClaims{
+	version, err := s.tokens.TokenVersion(ctx, claims.ID)
+	if err != nil {
+		log.Error("error while reading version of tokens")
+		return err
+	}
+	if claims.Version < version {
+		return repository.ErrTokenRevoked
+	}
+	return nil
+}
+
+// JWKS returns public keys for verification of tokens by other services
+func (s *UserAuthService) JWKS() JSONWebKeySet {
+	return s.keys.JWKS()
+}
+
+// SetUserRoleServ assigns 'role' to user, tokens issued before are revoked
+// so that user logs in again with new role
+func (s *UserAuthService) SetUserRoleServ(ctx context.Context, id uuid.UUID, role string) (models.User, error) {
+	user, err := s.repository.SetUserRole(ctx, id, role)
+	if err != nil {
+		return models.User{}, err
+	}
+	if _, err := s.tokens.BumpTokenVersion(ctx, id); err != nil {
+		log.Error("error while bumping version of tokens")
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// signTokens signs access and refresh tokens of user from 'claims', refresh token gets 'jti' from
+// 'claims' and access token gets a new one
+func (s *UserAuthService) signTokens(claims JwtCustomClaims, accessTTL, refreshTTL time.Duration) (t, rt string, err error) {
+	ac := &JwtCustomClaims{
+		ID:      claims.ID,
+		Name:    claims.Name,
+		Type:    TokenTypeAccess,
+		Version: claims.Version,
+		Role:    claims.Role,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Minute * natt).Unix(),
+			Id:        uuid.NewString(),
+			ExpiresAt: time.Now().Add(accessTTL).Unix(),
 		},
 	}
-	ntoken := jwt.NewWithClaims(jwt.SigningMethodHS256, ncl)
-
-	nt, err = ntoken.SignedString([]byte(s.cfg.KeyForSignatureJwt))
+	// Generate encoded token and send it as response.
+	t, err = s.keys.Sign(ac)
 	if err != nil {
-		log.Error("error during generate new token")
+		log.Error("error during generate token")
 		return "", "", err
 	}
 
-	nrfc := &JwtCustomClaims{
+	rfc := &JwtCustomClaims{
+		ID:      claims.ID,
+		Name:    claims.Name,
+		Type:    TokenTypeRefresh,
+		Family:  claims.Family,
+		Version: claims.Version,
+		Role:    claims.Role,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour * nrtt).Unix(),
+			Id:        claims.StandardClaims.Id,
+			ExpiresAt: time.Now().Add(refreshTTL).Unix(),
 		},
 	}
-	nrefToken := jwt.NewWithClaims(jwt.SigningMethodHS256, nrfc)
-
-	nrt, err = nrefToken.SignedString([]byte(s.cfg.KeyForSignatureJwt))
+	rt, err = s.keys.Sign(rfc)
 	if err != nil {
-		log.Error("error during generate new refresh token")
+		log.Error("error during generate refresh token")
 		return "", "", err
 	}
-	return nt, nrt, nil
-}
 
-// VerifyToken func does validation for entered tokens
-func VerifyToken(t string, cfg *configs.Config) (*jwt.Token, error) {
-	token, err := jwt.Parse(t, func(token *jwt.Token) (interface{}, error) {
-		// Make sure that the token method conform to "SigningMethodHMAC"
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(cfg.KeyForSignatureJwt), nil
-	})
-	if _, ok := token.Claims.(jwt.StandardClaims); !ok && !token.Valid {
-		return nil, err
-	}
+	return t, rt, nil
+}
 
+// VerifyToken func does validation for entered tokens, claims of valid token are *JwtCustomClaims
+func VerifyToken(t string, keys *KeySet) (*jwt.Token, error) {
+	token, err := jwt.ParseWithClaims(t, new(JwtCustomClaims), keys.Keyfunc)
 	if err != nil {
 		log.Error("error while verify token")
 		return nil, err
 	}
+	if !token.Valid {
+		return nil, ErrInvalidToken
+	}
 
 	return token, nil
 }
 
-func generatePassword(password string, cfg *configs.Config) string {
-	hash := sha256.New()
-	hash.Write([]byte(password))
-
-	return fmt.Sprintf("%x", hash.Sum([]byte(cfg.Salt)))
+// rehashPassword replaces legacy or outdated hash of password of user,
+// login doesn't fail if it's impossible
+func (s *UserAuthService) rehashPassword(ctx context.Context, user models.User, password string) {
+	hash, err := hashPassword(password, s.cfg)
+	if err != nil {
+		log.Error("error while rehashing password")
+		return
+	}
+	if err := s.repository.UpdatePassword(ctx, user.ID, hash); err != nil {
+		log.Error("error while updating password hash")
+	}
 }
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"CatsGo/internal/configs"
+	"CatsGo/internal/mailer"
+	"CatsGo/internal/models"
+	"CatsGo/internal/repository"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMailer keeps sent emails
+type recordingMailer struct {
+	sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// newAuthService returns service with 'rps', other dependencies are kept in memory
+func newAuthService(t *testing.T, rps repository.Auth, cfg *configs.Config) *UserAuthService {
+	s, err := NewUserAuthService(rps, repository.NewMemoryTokenStore(), repository.NewMemoryLoginAttempts(),
+		&recordingMailer{}, testKeySet(), cfg)
+	require.NoError(t, err)
+	return s
+}
+
+func newTestAuthService(t *testing.T) (*UserAuthService, models.User) {
+	s := newAuthService(t, repository.NewMemoryRepository(), testPasswordConfig())
+	user, err := s.CreateUserServ(context.Background(), models.User{Name: "Steve Jobs", Username: "steve", Password: "Stev13_jb7"})
+	require.NoError(t, err)
+	return s, user
+}
+
+func tokenClaims(t *testing.T, s *UserAuthService, token string) *JwtCustomClaims {
+	parsed, err := VerifyToken(token, s.keys)
+	require.NoError(t, err)
+	return parsed.Claims.(*JwtCustomClaims)
+}
+
+func TestUserAuthService_RefreshTokens(t *testing.T) {
+	ctx := context.Background()
+	s, user := newTestAuthService(t)
+
+	access, refresh, _, err := s.GenerateToken(ctx, "steve", "Stev13_jb7", "")
+	require.NoError(t, err)
+	assert.Equal(t, TokenTypeAccess, tokenClaims(t, s, access).Type)
+	assert.Equal(t, TokenTypeRefresh, tokenClaims(t, s, refresh).Type)
+
+	_, _, err = s.RefreshTokens(ctx, access)
+	assert.ErrorIs(t, err, ErrInvalidToken, "access token can't be used as refresh one")
+
+	newAccess, newRefresh, err := s.RefreshTokens(ctx, refresh)
+	require.NoError(t, err)
+	claims := tokenClaims(t, s, newAccess)
+	assert.Equal(t, user.ID, claims.ID, "refreshed access token keeps user")
+	assert.Equal(t, "steve", claims.Name)
+	assert.Equal(t, tokenClaims(t, s, refresh).Family, tokenClaims(t, s, newRefresh).Family)
+
+	// reuse of rotated token revokes the whole family
+	_, _, err = s.RefreshTokens(ctx, refresh)
+	assert.ErrorIs(t, err, repository.ErrTokenReused)
+	_, _, err = s.RefreshTokens(ctx, newRefresh)
+	assert.ErrorIs(t, err, repository.ErrTokenRevoked)
+
+	// other logins aren't affected
+	_, other, _, err := s.GenerateToken(ctx, "steve", "Stev13_jb7", "")
+	require.NoError(t, err)
+	_, _, err = s.RefreshTokens(ctx, other)
+	assert.NoError(t, err)
+}
+
+func TestUserAuthService_RefreshTokens_Invalid(t *testing.T) {
+	s, _ := newTestAuthService(t)
+
+	_, _, err := s.RefreshTokens(context.Background(), "not.a.token")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestUserAuthService_Logout(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestAuthService(t)
+
+	access, refresh, _, err := s.GenerateToken(ctx, "steve", "Stev13_jb7", "")
+	require.NoError(t, err)
+	otherAccess, otherRefresh, _, err := s.GenerateToken(ctx, "steve", "Stev13_jb7", "")
+	require.NoError(t, err)
+	claims := tokenClaims(t, s, access)
+	require.NoError(t, s.CheckToken(ctx, claims))
+
+	assert.ErrorIs(t, s.Logout(ctx, claims, access), ErrInvalidToken, "refresh token is required")
+	require.NoError(t, s.Logout(ctx, claims, refresh))
+
+	assert.ErrorIs(t, s.CheckToken(ctx, claims), repository.ErrTokenRevoked)
+	_, _, err = s.RefreshTokens(ctx, refresh)
+	assert.ErrorIs(t, err, repository.ErrTokenRevoked)
+
+	// tokens of other devices are still valid
+	assert.NoError(t, s.CheckToken(ctx, tokenClaims(t, s, otherAccess)))
+	_, _, err = s.RefreshTokens(ctx, otherRefresh)
+	assert.NoError(t, err)
+}
+
+func TestUserAuthService_LogoutAll(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestAuthService(t)
+
+	access, refresh, _, err := s.GenerateToken(ctx, "steve", "Stev13_jb7", "")
+	require.NoError(t, err)
+	otherAccess, _, _, err := s.GenerateToken(ctx, "steve", "Stev13_jb7", "")
+	require.NoError(t, err)
+
+	require.NoError(t, s.LogoutAll(ctx, tokenClaims(t, s, access)))
+	assert.ErrorIs(t, s.CheckToken(ctx, tokenClaims(t, s, access)), repository.ErrTokenRevoked)
+	assert.ErrorIs(t, s.CheckToken(ctx, tokenClaims(t, s, otherAccess)), repository.ErrTokenRevoked)
+	_, _, err = s.RefreshTokens(ctx, refresh)
+	assert.ErrorIs(t, err, repository.ErrTokenRevoked)
+
+	// tokens issued after logout are valid
+	access, refresh, _, err = s.GenerateToken(ctx, "steve", "Stev13_jb7", "")
+	require.NoError(t, err)
+	assert.NoError(t, s.CheckToken(ctx, tokenClaims(t, s, access)))
+	_, _, err = s.RefreshTokens(ctx, refresh)
+	assert.NoError(t, err)
+}
+
+func TestUserAuthService_SetUserRoleServ(t *testing.T) {
+	ctx := context.Background()
+	s, user := newTestAuthService(t)
+	assert.Equal(t, models.RoleViewer, user.Role, "new users are viewers")
+
+	access, _, _, err := s.GenerateToken(ctx, "steve", "Stev13_jb7", "")
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleViewer, tokenClaims(t, s, access).Role)
+
+	updated, err := s.SetUserRoleServ(ctx, user.ID, models.RoleStaff)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleStaff, updated.Role)
+	assert.ErrorIs(t, s.CheckToken(ctx, tokenClaims(t, s, access)), repository.ErrTokenRevoked,
+		"tokens with previous role are revoked")
+
+	access, _, _, err = s.GenerateToken(ctx, "steve", "Stev13_jb7", "")
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleStaff, tokenClaims(t, s, access).Role)
+
+	_, err = s.SetUserRoleServ(ctx, uuid.New(), models.RoleAdmin)
+	assert.ErrorIs(t, err, repository.ErrUserNotFound)
+}
+
+func TestUserAuthService_CreateUserServ_Admin(t *testing.T) {
+	cfg := testPasswordConfig()
+	cfg.AdminUsername = "root"
+	s := newAuthService(t, repository.NewMemoryRepository(), cfg)
+
+	admin, err := s.CreateUserServ(context.Background(), models.User{Name: "Admin", Username: "root", Password: "secret"})
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, admin.Role)
+
+	user, err := s.CreateUserServ(context.Background(), models.User{
+		Name: "Steve Jobs", Username: "steve", Password: "secret", Role: models.RoleAdmin,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleViewer, user.Role, "role can't be chosen on sign up")
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"CatsGo/internal/configs"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/gommon/log"
+)
+
+// errUnknownKey is returned when token is signed by key missing in KeySet
+var errUnknownKey = errors.New("unknown signing key")
+
+// verificationKey is a public key accepted for verification of tokens
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    crypto.PublicKey
+}
+
+// KeySet signs tokens by one private key and verifies them by all public keys of it, previous keys
+// stay for verification while tokens signed by them are alive, so keys can be rotated
+type KeySet struct {
+	kid        string
+	method     jwt.SigningMethod
+	signingKey interface{}
+	keys       map[string]verificationKey
+}
+
+// JSONWebKey is a public key in JWK format, RFC 7517
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// N and E are set for RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Crv and X are set for Ed25519 keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JSONWebKeySet contains public keys of KeySet
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// NewKeySet loads signing key from JWT_PRIVATE_KEY_FILE and previous public keys from
+// JWT_PUBLIC_KEY_FILES, without private key tokens are signed by HS256 with KEY_FOR_SIGNATURE_JWT
+func NewKeySet(cfg *configs.Config) (*KeySet, error) {
+	if cfg.JwtPrivateKeyFile == "" {
+		log.Warn("JWT_PRIVATE_KEY_FILE isn't set, tokens are signed by HS256 with shared secret")
+		return &KeySet{method: jwt.SigningMethodHS256, signingKey: []byte(cfg.KeyForSignatureJwt)}, nil
+	}
+
+	pem, err := os.ReadFile(cfg.JwtPrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	ks := &KeySet{keys: make(map[string]verificationKey)}
+	var public crypto.PublicKey
+	if rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(pem); err == nil {
+		ks.method, ks.signingKey, public = jwt.SigningMethodRS256, rsaKey, &rsaKey.PublicKey
+	} else if edKey, err := jwt.ParseEdPrivateKeyFromPEM(pem); err == nil {
+		ks.method, ks.signingKey, public = jwt.SigningMethodEdDSA, edKey, edKey.(ed25519.PrivateKey).Public()
+	} else {
+		return nil, fmt.Errorf("%s: private key isn't RSA or Ed25519", cfg.JwtPrivateKeyFile)
+	}
+	if ks.kid, err = ks.addKey(public); err != nil {
+		return nil, err
+	}
+
+	for _, file := range cfg.JwtPublicKeyFiles {
+		pem, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if rsaKey, err := jwt.ParseRSAPublicKeyFromPEM(pem); err == nil {
+			public = rsaKey
+		} else if edKey, err := jwt.ParseEdPublicKeyFromPEM(pem); err == nil {
+			public = edKey
+		} else {
+			return nil, fmt.Errorf("%s: public key isn't RSA or Ed25519", file)
+		}
+		if _, err := ks.addKey(public); err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+// addKey adds public key for verification, 'kid' of key is its thumbprint
+func (ks *KeySet) addKey(public crypto.PublicKey) (kid string, err error) {
+	var method jwt.SigningMethod
+	switch public.(type) {
+	case *rsa.PublicKey:
+		method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return "", errors.New("unsupported type of public key")
+	}
+	kid = thumbprint(publicJWK(public, "", method))
+	ks.keys[kid] = verificationKey{method: method, key: public}
+	return kid, nil
+}
+
+// Sign signs token with 'claims' by current key
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.method, claims)
+	if ks.kid != "" {
+		token.Header["kid"] = ks.kid
+	}
+	return token.SignedString(ks.signingKey)
+}
+
+// Keyfunc returns key for verification of token by its 'kid' header
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if ks.kid == "" {
+		// Make sure that the token method conform to "SigningMethodHMAC"
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return ks.signingKey, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, errUnknownKey
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.key, nil
+}
+
+// JWKS returns public keys for verification of tokens, it's empty for HS256
+func (ks *KeySet) JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(ks.keys))}
+	for kid, key := range ks.keys {
+		set.Keys = append(set.Keys, publicJWK(key.key, kid, key.method))
+	}
+	// current key goes first
+	sort.Slice(set.Keys, func(i, j int) bool {
+		if (set.Keys[i].Kid == ks.kid) != (set.Keys[j].Kid == ks.kid) {
+			return set.Keys[i].Kid == ks.kid
+		}
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+	return set
+}
+
+// publicJWK converts public key to JWK
+func publicJWK(public crypto.PublicKey, kid string, method jwt.SigningMethod) JSONWebKey {
+	jwk := JSONWebKey{Kid: kid, Use: "sig", Alg: method.Alg()}
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty, jwk.Crv = "OKP", "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(key)
+	}
+	return jwk
+}
+
+// thumbprint computes JWK thumbprint, RFC 7638
+func thumbprint(jwk JSONWebKey) string {
+	var members interface{}
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"CatsGo/internal/configs"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testKeySet signs tokens by new Ed25519 key
+func testKeySet() *KeySet {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	ks := &KeySet{method: jwt.SigningMethodEdDSA, signingKey: private, keys: make(map[string]verificationKey)}
+	if ks.kid, err = ks.addKey(public); err != nil {
+		panic(err)
+	}
+	return ks
+}
+
+// writeKeys saves private and public keys as PEM files
+func writeKeys(t *testing.T, private crypto.Signer) (privateFile, publicFile string) {
+	dir := t.TempDir()
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	privateFile = filepath.Join(dir, "private.pem")
+	require.NoError(t, os.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+
+	der, err = x509.MarshalPKIXPublicKey(private.Public())
+	require.NoError(t, err)
+	publicFile = filepath.Join(dir, "public.pem")
+	require.NoError(t, os.WriteFile(publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+	return privateFile, publicFile
+}
+
+func testClaims() *JwtCustomClaims {
+	return &JwtCustomClaims{
+		Name:           "steve",
+		Type:           TokenTypeAccess,
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Minute).Unix()},
+	}
+}
+
+func TestKeySet(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaPrivate, _ := writeKeys(t, rsaKey)
+	edPrivate, _ := writeKeys(t, edKey)
+
+	type TestCase struct {
+		name       string
+		privateKey string
+		expectAlg  string
+		expectKty  string
+	}
+	TestTable := []TestCase{
+		{name: "RS256", privateKey: rsaPrivate, expectAlg: "RS256", expectKty: "RSA"},
+		{name: "EdDSA", privateKey: edPrivate, expectAlg: "EdDSA", expectKty: "OKP"},
+	}
+	for _, testCase := range TestTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			ks, err := NewKeySet(&configs.Config{JwtPrivateKeyFile: testCase.privateKey})
+			require.NoError(t, err)
+
+			signed, err := ks.Sign(testClaims())
+			require.NoError(t, err)
+			token, err := VerifyToken(signed, ks)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectAlg, token.Header["alg"])
+			assert.Equal(t, ks.kid, token.Header["kid"])
+
+			jwks := ks.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, ks.kid, jwks.Keys[0].Kid)
+			assert.Equal(t, testCase.expectKty, jwks.Keys[0].Kty)
+			assert.Equal(t, testCase.expectAlg, jwks.Keys[0].Alg)
+		})
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	oldPrivate, oldPublic := writeKeys(t, rsaKey)
+	newPrivate, _ := writeKeys(t, edKey)
+
+	old, err := NewKeySet(&configs.Config{JwtPrivateKeyFile: oldPrivate})
+	require.NoError(t, err)
+	signedByOld, err := old.Sign(testClaims())
+	require.NoError(t, err)
+
+	rotated, err := NewKeySet(&configs.Config{JwtPrivateKeyFile: newPrivate, JwtPublicKeyFiles: []string{oldPublic}})
+	require.NoError(t, err)
+	_, err = VerifyToken(signedByOld, rotated)
+	assert.NoError(t, err, "tokens signed by previous key are accepted")
+	assert.Len(t, rotated.JWKS().Keys, 2)
+	assert.Equal(t, rotated.kid, rotated.JWKS().Keys[0].Kid, "current key goes first")
+
+	withoutOld, err := NewKeySet(&configs.Config{JwtPrivateKeyFile: newPrivate})
+	require.NoError(t, err)
+	_, err = VerifyToken(signedByOld, withoutOld)
+	assert.Error(t, err, "tokens signed by removed key are rejected")
+}
+
+func TestKeySet_HMAC(t *testing.T) {
+	hmac, err := NewKeySet(&configs.Config{KeyForSignatureJwt: "secret"})
+	require.NoError(t, err)
+	signed, err := hmac.Sign(testClaims())
+	require.NoError(t, err)
+	_, err = VerifyToken(signed, hmac)
+	assert.NoError(t, err)
+	assert.Empty(t, hmac.JWKS().Keys)
+
+	// token signed by shared secret isn't accepted when keys are asymmetric
+	_, err = VerifyToken(signed, testKeySet())
+	assert.Error(t, err)
+	// and token signed by asymmetric key isn't accepted in HS256 mode
+	signed, err = testKeySet().Sign(testClaims())
+	require.NoError(t, err)
+	_, err = VerifyToken(signed, hmac)
+	assert.Error(t, err)
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"CatsGo/internal/apperror"
+	"CatsGo/internal/models"
+	"CatsGo/internal/repository"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+)
+
+// mfaTokenTTL is a lifetime of challenge token returned by login when TOTP is enabled
+const mfaTokenTTL = 5 * time.Minute
+
+var (
+	// ErrMFAEnabled is returned on enrollment when TOTP is already enabled
+	ErrMFAEnabled = apperror.New(apperror.Conflict, "two-factor authentication is already enabled")
+	// ErrMFANotEnrolled is returned when user hasn't enrolled or enabled TOTP
+	ErrMFANotEnrolled = apperror.New(apperror.Conflict, "two-factor authentication isn't enrolled")
+	// ErrInvalidMFACode is returned when neither TOTP nor recovery code matches
+	ErrInvalidMFACode = apperror.New(apperror.Validation, "invalid two-factor authentication code")
+)
+
+// EnrollTOTPServ generates new TOTP secret of user, it's used on login only after ConfirmTOTPServ
+func (s *UserAuthService) EnrollTOTPServ(ctx context.Context, userID uuid.UUID) (secret, uri string, err error) {
+	user, err := s.repository.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+	if user.TOTPEnabled {
+		return "", "", ErrMFAEnabled
+	}
+	secret, err = newTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.repository.SetTOTPSecret(ctx, userID, secret); err != nil {
+		log.Error("error while saving totp secret")
+		return "", "", err
+	}
+	return secret, totpURI(s.cfg.TOTPIssuer, user.Username, secret), nil
+}
+
+// ConfirmTOTPServ enables TOTP of user if 'code' matches enrolled secret, recovery codes are returned only here
+func (s *UserAuthService) ConfirmTOTPServ(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.repository.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+	if err := s.useTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repository.EnableTOTP(ctx, userID, hashes); err != nil {
+		log.Error("error while enabling totp")
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTPServ turns off TOTP of user, 'code' is TOTP or recovery code
+func (s *UserAuthService) DisableTOTPServ(ctx context.Context, userID uuid.UUID, code string) error {
+	user, err := s.repository.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrMFANotEnrolled
+	}
+	if err := s.checkMFACode(ctx, user, code); err != nil {
+		return err
+	}
+	return s.repository.DisableTOTP(ctx, userID)
+}
+
+// LoginMFA exchanges challenge token returned by GenerateToken and TOTP or recovery 'code' for a pair of tokens,
+// every challenge token can be used once
+func (s *UserAuthService) LoginMFA(ctx context.Context, mfaToken, code string) (t, rt string, err error) {
+	verifyResult, err := VerifyToken(mfaToken, s.keys)
+	if err != nil {
+		return "", "", ErrInvalidToken
+	}
+	claims := verifyResult.Claims.(*JwtCustomClaims)
+	if claims.Type != TokenTypeMFA {
+		return "", "", ErrInvalidToken
+	}
+	if err := s.CheckToken(ctx, claims); err != nil {
+		return "", "", err
+	}
+	user, err := s.repository.GetUserByID(ctx, claims.ID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return "", "", ErrInvalidToken
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if !user.TOTPEnabled {
+		return "", "", ErrInvalidToken
+	}
+	// guessing of codes is throttled like guessing of passwords
+	limits := s.loginLimits(user.Username, "")
+	if err := s.checkLoginAllowed(ctx, limits); err != nil {
+		return "", "", err
+	}
+	err = s.checkMFACode(ctx, user, code)
+	if errors.Is(err, ErrInvalidMFACode) {
+		if err := s.loginFailed(ctx, limits); err != nil {
+			return "", "", err
+		}
+		// wrong code fails login unlike in enrollment
+		return "", "", apperror.Wrap(apperror.Unauthorized, ErrInvalidMFACode)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	s.loginSucceeded(ctx, limits)
+
+	ttl := time.Until(time.Unix(claims.ExpiresAt, 0))
+	if err := s.tokens.BlacklistToken(ctx, claims.StandardClaims.Id, ttl); err != nil {
+		log.Error("error while blacklisting mfa token")
+		return "", "", err
+	}
+	return s.issueTokens(ctx, user, claims.Version)
+}
+
+// checkMFACode returns ErrInvalidMFACode unless 'code' is current TOTP of user or one of unused recovery codes,
+// both of them can be used once
+func (s *UserAuthService) checkMFACode(ctx context.Context, user models.User, code string) error {
+	err := s.useTOTP(ctx, user, code)
+	if !errors.Is(err, ErrInvalidMFACode) {
+		return err
+	}
+	err = s.repository.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(code))
+	if errors.Is(err, repository.ErrRecoveryCodeNotFound) {
+		return ErrInvalidMFACode
+	}
+	if err != nil {
+		log.Error("error while using recovery code")
+		return err
+	}
+	log.Infof("recovery code of user %s is used", user.ID)
+	return nil
+}
+
+// useTOTP returns ErrInvalidMFACode unless 'code' is current TOTP of user which isn't used yet,
+// time step of accepted code is saved so that the code can't be replayed
+func (s *UserAuthService) useTOTP(ctx context.Context, user models.User, code string) error {
+	counter, ok := validateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastCounter)
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	err := s.repository.UseTOTPCounter(ctx, user.ID, counter)
+	if errors.Is(err, repository.ErrTOTPCodeUsed) {
+		return ErrInvalidMFACode
+	}
+	if err != nil {
+		log.Error("error while saving time step of totp code")
+		return err
+	}
+	return nil
+}
+
+// signMFAToken signs short-lived challenge token of user which is exchanged for tokens by LoginMFA
+func (s *UserAuthService) signMFAToken(user models.User, version int64) (string, error) {
+	return s.keys.Sign(&JwtCustomClaims{
+		ID:      user.ID,
+		Name:    user.Username,
+		Type:    TokenTypeMFA,
+		Version: version,
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.NewString(),
+			ExpiresAt: time.Now().Add(mfaTokenTTL).Unix(),
+		},
+	})
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"CatsGo/internal/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// totpAt returns TOTP code of base32 'secret' of time step 'skew' periods away from current one,
+// every code is accepted once so tests take codes of later steps one by one
+func totpAt(t *testing.T, secret string, skew int64) string {
+	return totpOfStep(t, secret, time.Now().Unix()/totpPeriod+skew)
+}
+
+// totpOfStep returns TOTP code of base32 'secret' of time step 'step'
+func totpOfStep(t *testing.T, secret string, step int64) string {
+	key, err := base32NoPadding.DecodeString(secret)
+	require.NoError(t, err)
+	return hotp(key, uint64(step))
+}
+
+// enableTOTP enrolls and confirms TOTP of user, it returns secret and recovery codes
+func enableTOTP(t *testing.T, s *UserAuthService, user models.User) (secret string, codes []string) {
+	ctx := context.Background()
+	secret, uri, err := s.EnrollTOTPServ(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Contains(t, uri, "secret="+secret)
+
+	_, err = s.ConfirmTOTPServ(ctx, user.ID, "000000x")
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+	codes, err = s.ConfirmTOTPServ(ctx, user.ID, totpAt(t, secret, -1))
+	require.NoError(t, err)
+	return secret, codes
+}
+
+func TestUserAuthService_LoginMFA(t *testing.T) {
+	ctx := context.Background()
+	s, user := newTestAuthService(t)
+	secret, _ := enableTOTP(t, s, user)
+
+	_, _, err := s.EnrollTOTPServ(ctx, user.ID)
+	assert.ErrorIs(t, err, ErrMFAEnabled, "enabled TOTP can't be replaced")
+
+	access, refresh, mfaToken, err := s.GenerateToken(ctx, "steve", "Stev13_jb7", "")
+	require.NoError(t, err)
+	assert.Empty(t, access)
+	assert.Empty(t, refresh)
+	assert.Equal(t, TokenTypeMFA, tokenClaims(t, s, mfaToken).Type)
+
+	_, _, err = s.LoginMFA(ctx, mfaToken, "000000x")
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+	access, refresh, err = s.LoginMFA(ctx, mfaToken, totpAt(t, secret, 0))
+	require.NoError(t, err)
+	assert.Equal(t, TokenTypeAccess, tokenClaims(t, s, access).Type)
+	_, _, err = s.RefreshTokens(ctx, refresh)
+	assert.NoError(t, err)
+
+	_, _, err = s.LoginMFA(ctx, mfaToken, totpAt(t, secret, 1))
+	assert.Error(t, err, "challenge token can be used once")
+	_, _, err = s.LoginMFA(ctx, access, totpAt(t, secret, 1))
+	assert.ErrorIs(t, err, ErrInvalidToken, "access token isn't a challenge")
+}
+
+func TestUserAuthService_LoginMFA_Replay(t *testing.T) {
+	ctx := context.Background()
+	s, user := newTestAuthService(t)
+	secret, _ := enableTOTP(t, s, user)
+	// steps are counted from the one of code used on confirmation, the next two of it are accepted
+	user, err := s.repository.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	confirmed := user.TOTPLastCounter
+
+	TestTable := []struct {
+		name        string
+		code        string
+		exceptError error
+	}{
+		{
+			name:        "code used on confirmation",
+			code:        totpOfStep(t, secret, confirmed),
+			exceptError: ErrInvalidMFACode,
+		},
+		{
+			name: "next code",
+			code: totpOfStep(t, secret, confirmed+1),
+		},
+		{
+			name:        "replayed code",
+			code:        totpOfStep(t, secret, confirmed+1),
+			exceptError: ErrInvalidMFACode,
+		},
+		{
+			name: "code of later step",
+			code: totpOfStep(t, secret, confirmed+2),
+		},
+		{
+			name:        "code of earlier step",
+			code:        totpOfStep(t, secret, confirmed+1),
+			exceptError: ErrInvalidMFACode,
+		},
+	}
+
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			_, _, mfaToken, err := s.GenerateToken(ctx, "steve", "Stev13_jb7", "")
+			require.NoError(t, err)
+			_, _, err = s.LoginMFA(ctx, mfaToken, TestCase.code)
+			if TestCase.exceptError != nil {
+				assert.ErrorIs(t, err, TestCase.exceptError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestUserAuthService_LoginMFA_RecoveryCode(t *testing.T) {
+	ctx := context.Background()
+	s, user := newTestAuthService(t)
+	_, codes := enableTOTP(t, s, user)
+
+	_, _, mfaToken, err := s.GenerateToken(ctx, "steve", "Stev13_jb7", "")
+	require.NoError(t, err)
+	_, _, err = s.LoginMFA(ctx, mfaToken, codes[0])
+	require.NoError(t, err)
+
+	_, _, mfaToken, err = s.GenerateToken(ctx, "steve", "Stev13_jb7", "")
+	require.NoError(t, err)
+	_, _, err = s.LoginMFA(ctx, mfaToken, codes[0])
+	assert.ErrorIs(t, err, ErrInvalidMFACode, "recovery code can be used once")
+	_, _, err = s.LoginMFA(ctx, mfaToken, codes[1])
+	assert.NoError(t, err)
+}
+
+func TestUserAuthService_DisableTOTPServ(t *testing.T) {
+	ctx := context.Background()
+	s, user := newTestAuthService(t)
+	assert.ErrorIs(t, s.DisableTOTPServ(ctx, user.ID, "123456"), ErrMFANotEnrolled)
+
+	_, err := s.ConfirmTOTPServ(ctx, user.ID, "123456")
+	assert.ErrorIs(t, err, ErrMFANotEnrolled, "TOTP is confirmed after enrollment")
+
+	secret, _ := enableTOTP(t, s, user)
+	assert.ErrorIs(t, s.DisableTOTPServ(ctx, user.ID, "000000x"), ErrInvalidMFACode)
+	require.NoError(t, s.DisableTOTPServ(ctx, user.ID, totpAt(t, secret, 0)))
+
+	access, _, mfaToken, err := s.GenerateToken(ctx, "steve", "Stev13_jb7", "")
+	require.NoError(t, err)
+	assert.NotEmpty(t, access)
+	assert.Empty(t, mfaToken)
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"CatsGo/internal/configs"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+var (
+	// ErrIncorrectPassword is returned when password doesn't match the stored hash
+	ErrIncorrectPassword = errors.New("incorrect password")
+	// errInvalidHash is returned when stored hash has unknown format
+	errInvalidHash = errors.New("invalid format of password hash")
+)
+
+// argon2Params contains parameters of argon2id encoded in hash
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+func paramsFromConfig(cfg *configs.Config) argon2Params {
+	return argon2Params{memory: cfg.Argon2Memory, time: cfg.Argon2Time, threads: cfg.Argon2Threads}
+}
+
+// hashPassword hashes password by argon2id with random salt, result is encoded
+// as $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<hash>
+func hashPassword(password string, cfg *configs.Config) (string, error) {
+	params := paramsFromConfig(cfg)
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, argon2KeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		params.memory, params.time, params.threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// verifyPassword compares password with stored hash in constant time, 'rehash' reports
+// whether hash is legacy or made with outdated parameters and has to be replaced
+func verifyPassword(password, hash string, cfg *configs.Config) (rehash bool, err error) {
+	if !strings.HasPrefix(hash, "$") {
+		// legacy hashes are hex of salt followed by SHA-256 of password
+		if subtle.ConstantTimeCompare([]byte(legacyPasswordHash(password, cfg)), []byte(hash)) != 1 {
+			return false, ErrIncorrectPassword
+		}
+		return true, nil
+	}
+
+	params, salt, key, err := decodeHash(hash)
+	if err != nil {
+		return false, err
+	}
+	other := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return false, ErrIncorrectPassword
+	}
+	return params != paramsFromConfig(cfg), nil
+}
+
+// decodeHash parses hash made by hashPassword
+func decodeHash(hash string) (params argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, errInvalidHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errInvalidHash
+	}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads)
+	if err != nil {
+		return params, nil, nil, errInvalidHash
+	}
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, errInvalidHash
+	}
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errInvalidHash
+	}
+	return params, salt, key, nil
+}
+
+// legacyPasswordHash is the way passwords were hashed before argon2id
+func legacyPasswordHash(password string, cfg *configs.Config) string {
+	hash := sha256.New()
+	hash.Write([]byte(password))
+
+	return fmt.Sprintf("%x", hash.Sum([]byte(cfg.Salt)))
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"CatsGo/internal/configs"
+	"CatsGo/internal/models"
+	"CatsGo/internal/repository"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPasswordConfig() *configs.Config {
+	return &configs.Config{Salt: "l337c0d3", Argon2Memory: 1024, Argon2Time: 1, Argon2Threads: 1, KeyForSignatureJwt: "test"}
+}
+
+func TestHashPassword(t *testing.T) {
+	cfg := testPasswordConfig()
+
+	hash, err := hashPassword("Stev13_jb7", cfg)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	other, err := hashPassword("Stev13_jb7", cfg)
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "every hash has its own salt")
+
+	outdated := testPasswordConfig()
+	outdated.Argon2Time = 2
+	outdatedHash, err := hashPassword("Stev13_jb7", outdated)
+	require.NoError(t, err)
+
+	type TestCase struct {
+		name         string
+		password     string
+		hash         string
+		expectRehash bool
+		exceptError  error
+	}
+	TestTable := []TestCase{
+		{name: "OK", password: "Stev13_jb7", hash: hash},
+		{name: "incorrect password", password: "random", hash: hash, exceptError: ErrIncorrectPassword},
+		{name: "outdated parameters", password: "Stev13_jb7", hash: outdatedHash, expectRehash: true},
+		{name: "legacy hash", password: "Stev13_jb7", hash: legacyPasswordHash("Stev13_jb7", cfg), expectRehash: true},
+		{
+			name: "incorrect password for legacy hash", password: "random",
+			hash: legacyPasswordHash("Stev13_jb7", cfg), exceptError: ErrIncorrectPassword,
+		},
+		{name: "invalid hash", password: "Stev13_jb7", hash: "$argon2id$v=19$broken", exceptError: errInvalidHash},
+	}
+	for _, testCase := range TestTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			rehash, err := verifyPassword(testCase.password, testCase.hash, cfg)
+			assert.ErrorIs(t, err, testCase.exceptError)
+			assert.Equal(t, testCase.expectRehash, rehash)
+		})
+	}
+}
+
+func TestUserAuthService_GenerateToken_Rehash(t *testing.T) {
+	ctx := context.Background()
+	cfg := testPasswordConfig()
+	rps := repository.NewMemoryRepository()
+	_, err := rps.CreateUser(ctx, models.User{
+		Name: "Steve Jobs", Username: "steve", Password: legacyPasswordHash("Stev13_jb7", cfg),
+	})
+	require.NoError(t, err)
+	s := newAuthService(t, rps, cfg)
+
+	_, _, _, err = s.GenerateToken(ctx, "steve", "random", "")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, _, _, err = s.GenerateToken(ctx, "steve", "Stev13_jb7", "")
+	require.NoError(t, err)
+	user, err := rps.GetUser(ctx, "steve")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(user.Password, "$argon2id$"), "legacy hash is upgraded on login")
+
+	_, _, _, err = s.GenerateToken(ctx, "steve", "Stev13_jb7", "")
+	assert.NoError(t, err, "upgraded hash is accepted")
+}
+
+func TestNewUserAuthService_DummyHash(t *testing.T) {
+	cfg := testPasswordConfig()
+	s := newAuthService(t, repository.NewMemoryRepository(), cfg)
+
+	// unknown users are checked against hash of the same cost as hashes of real users
+	rehash, err := verifyPassword("random", s.dummyHash, cfg)
+	assert.ErrorIs(t, err, ErrIncorrectPassword)
+	assert.False(t, rehash)
+
+	_, _, _, err = s.GenerateToken(context.Background(), "nobody", "random", "")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
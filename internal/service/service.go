@@ -2,72 +2,107 @@
 package service
 
 import (
+	"CatsGo/internal/apperror"
 	"CatsGo/internal/models"
 	"CatsGo/internal/repository"
+	"context"
 
 	"github.com/labstack/gommon/log"
 
 	"github.com/google/uuid"
 )
 
+// ErrForbidden is returned when user isn't allowed to change cat
+var ErrForbidden = apperror.New(apperror.Forbidden, "only owner or admin can change cat")
+
+// Actor is an authenticated user making request
+type Actor struct {
+	ID   uuid.UUID
+	Role string
+}
+
 // CatService interface of repository
 type CatService struct {
 	repository repository.Repository
-	redisrepo  repository.RedisRepository
 }
 
 // Service contains methods which get params from handler and sent them to repository
 type Service interface {
-	GetAllCatsServ() ([]*models.Cats, error)
-	CreateCatServ(cats models.Cats) (*models.Cats, error)
-	GetCatServ(id uuid.UUID) (*models.Cats, error)
-	UpdateCatServ(id uuid.UUID, cats models.Cats) (*models.Cats, error)
-	DeleteCatServ(id uuid.UUID) error
+	GetAllCatsServ(ctx context.Context, query models.CatsQuery) (*models.CatsPage, error)
+	CreateCatServ(ctx context.Context, actor Actor, cats models.Cats) (*models.Cats, error)
+	GetCatServ(ctx context.Context, id uuid.UUID) (*models.Cats, error)
+	UpdateCatServ(ctx context.Context, actor Actor, id uuid.UUID, cats models.Cats) (*models.Cats, error)
+	DeleteCatServ(ctx context.Context, actor Actor, id uuid.UUID) error
+	SearchCatsServ(ctx context.Context, query string, limit int) ([]*models.Cats, error)
 }
 
 // NewCatService constructor
-func NewCatService(rps repository.Repository, redisrps repository.RedisRepository) *CatService {
-	return &CatService{repository: rps, redisrepo: redisrps}
+func NewCatService(rps repository.Repository) *CatService {
+	return &CatService{repository: rps}
 }
 
 // GetAllCatsServ called by handler and calls func in repository
-func (s *CatService) GetAllCatsServ() ([]*models.Cats, error) {
-	return s.repository.GetAllCats()
+func (s *CatService) GetAllCatsServ(ctx context.Context, query models.CatsQuery) (*models.CatsPage, error) {
+	return s.repository.GetAllCats(ctx, query)
 }
 
-// CreateCatServ called by handler and calls func in repository
-func (s *CatService) CreateCatServ(cats models.Cats) (*models.Cats, error) {
-	err := s.redisrepo.CreateCat(cats)
-	if err != nil {
-		log.Error(err)
-	}
-	return s.repository.CreateCat(cats)
+// CreateCatServ called by handler and calls func in repository, 'actor' becomes owner of cat
+func (s *CatService) CreateCatServ(ctx context.Context, actor Actor, cats models.Cats) (*models.Cats, error) {
+	owner := actor.ID
+	cats.OwnerID = &owner
+	return s.repository.CreateCat(ctx, withDefaults(cats))
 }
 
 // GetCatServ called by handler and calls func in repository
-func (s *CatService) GetCatServ(id uuid.UUID) (*models.Cats, error) {
-	cat, err := s.redisrepo.GetCat(id)
+func (s *CatService) GetCatServ(ctx context.Context, id uuid.UUID) (*models.Cats, error) {
+	cat, err := s.repository.GetCat(ctx, id)
 	if err != nil {
-		cat, err = s.repository.GetCat(id)
-		if err != nil {
-			log.Error(err)
-			return nil, err
-		}
+		log.Error(err)
+		return nil, err
 	}
 	return cat, nil
 }
 
-// UpdateCatServ called by handler and calls func in repository
-func (s *CatService) UpdateCatServ(id uuid.UUID, cats models.Cats) (*models.Cats, error) {
-	return s.repository.UpdateCat(id, cats)
+// UpdateCatServ called by handler and calls func in repository if 'actor' may change cat
+func (s *CatService) UpdateCatServ(ctx context.Context, actor Actor, id uuid.UUID, cats models.Cats) (*models.Cats, error) {
+	if err := s.authorize(ctx, actor, id); err != nil {
+		return nil, err
+	}
+	return s.repository.UpdateCat(ctx, id, withDefaults(cats))
+}
+
+// DeleteCatServ called by handler and calls func in repository if 'actor' may change cat
+func (s *CatService) DeleteCatServ(ctx context.Context, actor Actor, id uuid.UUID) error {
+	if err := s.authorize(ctx, actor, id); err != nil {
+		return err
+	}
+	return s.repository.DeleteCat(ctx, id)
 }
 
-// DeleteCatServ called by handler and calls func in repository
-func (s *CatService) DeleteCatServ(id uuid.UUID) error {
-	err := s.redisrepo.DeleteCat(id)
+// authorize returns ErrForbidden unless 'actor' is admin or owner of cat
+func (s *CatService) authorize(ctx context.Context, actor Actor, id uuid.UUID) error {
+	if actor.Role == models.RoleAdmin {
+		return nil
+	}
+	cat, err := s.repository.GetCat(ctx, id)
 	if err != nil {
-		log.Error(err)
 		return err
 	}
-	return s.repository.DeleteCat(id)
+	if cat.OwnerID == nil || *cat.OwnerID != actor.ID {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// SearchCatsServ called by handler and calls func in repository
+func (s *CatService) SearchCatsServ(ctx context.Context, query string, limit int) ([]*models.Cats, error) {
+	return s.repository.SearchCats(ctx, query, limit)
+}
+
+// withDefaults fills optional fields of cat missing in request
+func withDefaults(cats models.Cats) models.Cats {
+	if cats.Status == "" {
+		cats.Status = models.CatStatusAvailable
+	}
+	return cats
 }
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"CatsGo/internal/models"
+	"CatsGo/internal/repository"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatService_Ownership(t *testing.T) {
+	ctx := context.Background()
+	s := NewCatService(repository.NewMemoryRepository())
+	owner := Actor{ID: uuid.New(), Role: models.RoleViewer}
+	other := Actor{ID: uuid.New(), Role: models.RoleStaff}
+	admin := Actor{ID: uuid.New(), Role: models.RoleAdmin}
+
+	cat, err := s.CreateCatServ(ctx, owner, models.Cats{Name: "Barsik", OwnerID: &other.ID})
+	require.NoError(t, err)
+	require.NotNil(t, cat.OwnerID)
+	assert.Equal(t, owner.ID, *cat.OwnerID, "owner is taken from actor, not from request")
+
+	type TestCase struct {
+		name        string
+		actor       Actor
+		exceptError error
+	}
+	TestTable := []TestCase{
+		{name: "other user", actor: other, exceptError: ErrForbidden},
+		{name: "owner", actor: owner},
+		{name: "admin", actor: admin},
+	}
+	for _, testCase := range TestTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			updated, err := s.UpdateCatServ(ctx, testCase.actor, cat.ID, models.Cats{Name: "Pushok"})
+			assert.ErrorIs(t, err, testCase.exceptError)
+			if testCase.exceptError == nil {
+				assert.Equal(t, owner.ID, *updated.OwnerID, "update keeps owner")
+			}
+		})
+	}
+
+	assert.ErrorIs(t, s.DeleteCatServ(ctx, other, cat.ID), ErrForbidden)
+	require.NoError(t, s.DeleteCatServ(ctx, owner, cat.ID))
+	assert.ErrorIs(t, s.DeleteCatServ(ctx, owner, cat.ID), repository.ErrCatNotFound)
+}
+
+func TestCatService_MyCats(t *testing.T) {
+	ctx := context.Background()
+	s := NewCatService(repository.NewMemoryRepository())
+	owner := Actor{ID: uuid.New(), Role: models.RoleViewer}
+	other := Actor{ID: uuid.New(), Role: models.RoleViewer}
+
+	for _, name := range []string{"Barsik", "Pushok"} {
+		_, err := s.CreateCatServ(ctx, owner, models.Cats{Name: name})
+		require.NoError(t, err)
+	}
+	_, err := s.CreateCatServ(ctx, other, models.Cats{Name: "Snejok"})
+	require.NoError(t, err)
+
+	page, err := s.GetAllCatsServ(ctx, models.CatsQuery{Limit: 10, OwnerID: &owner.ID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), page.Total)
+	for _, cat := range page.Cats {
+		assert.Equal(t, owner.ID, *cat.OwnerID)
+	}
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"CatsGo/internal/apperror"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/labstack/gommon/log"
+)
+
+var (
+	// ErrInvalidCredentials is returned by login for unknown username and incorrect password alike
+	ErrInvalidCredentials = apperror.New(apperror.Unauthorized, "invalid username or password")
+	// ErrTooManyAttempts is wrapped by ThrottledError
+	ErrTooManyAttempts = apperror.New(apperror.TooManyRequests, "too many failed login attempts")
+)
+
+// ThrottledError is returned when logins are blocked after failed attempts
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+// Unwrap makes ThrottledError match ErrTooManyAttempts and its kind
+func (e *ThrottledError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// loginLimit is a policy of failed logins by one key of LoginAttempts
+type loginLimit struct {
+	key string
+	// free is a number of failures allowed without delay
+	free int64
+	// lockout is a number of failures locking logins out
+	lockout int64
+}
+
+// loginLimits returns limits of failed logins of 'username' and from 'ip'
+func (s *UserAuthService) loginLimits(username, ip string) []loginLimit {
+	limits := []loginLimit{{
+		key: "user:" + strings.ToLower(username), free: s.cfg.LoginFreeAttempts, lockout: s.cfg.LoginLockoutAttempts,
+	}}
+	if ip != "" {
+		limits = append(limits, loginLimit{
+			key: "ip:" + ip, free: s.cfg.LoginIPFreeAttempts, lockout: s.cfg.LoginIPLockoutAttempts,
+		})
+	}
+	return limits
+}
+
+// checkLoginAllowed returns ThrottledError if logins by any of 'limits' are blocked
+func (s *UserAuthService) checkLoginAllowed(ctx context.Context, limits []loginLimit) error {
+	var retryAfter time.Duration
+	for _, limit := range limits {
+		left, err := s.attempts.BlockedFor(ctx, limit.key)
+		if err != nil {
+			log.Error("error while checking blocked logins")
+			return err
+		}
+		if left > retryAfter {
+			retryAfter = left
+		}
+	}
+	if retryAfter > 0 {
+		return &ThrottledError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// loginFailed counts failed login by all 'limits' and blocks next logins by them with exponential backoff,
+// logins are locked out for LoginLockoutDuration after 'lockout' failures
+func (s *UserAuthService) loginFailed(ctx context.Context, limits []loginLimit) error {
+	for _, limit := range limits {
+		failures, err := s.attempts.RegisterFailure(ctx, limit.key, s.cfg.LoginFailureWindow)
+		if err != nil {
+			log.Error("error while counting failed login")
+			return err
+		}
+		delay := s.loginBackoff(limit, failures)
+		if delay <= 0 {
+			continue
+		}
+		if err := s.attempts.Block(ctx, limit.key, delay); err != nil {
+			log.Error("error while blocking logins")
+			return err
+		}
+		if limit.lockout > 0 && failures >= limit.lockout {
+			log.Warnf("audit: logins by %s are locked out for %s after %d failed attempts",
+				limit.key, delay, failures)
+		}
+	}
+	return nil
+}
+
+// loginBackoff returns time logins are blocked for after 'failures' failed attempts
+func (s *UserAuthService) loginBackoff(limit loginLimit, failures int64) time.Duration {
+	if limit.lockout > 0 && failures >= limit.lockout {
+		return s.cfg.LoginLockoutDuration
+	}
+	if failures <= limit.free {
+		return 0
+	}
+	delay := s.cfg.LoginBackoffBase
+	for i := limit.free + 1; i < failures && delay < s.cfg.LoginBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > s.cfg.LoginBackoffMax {
+		delay = s.cfg.LoginBackoffMax
+	}
+	return delay
+}
+
+// loginSucceeded forgets failed logins of user, failures from IP are kept
+// so that a single known password doesn't unlock guessing of others
+func (s *UserAuthService) loginSucceeded(ctx context.Context, limits []loginLimit) {
+	if err := s.attempts.ResetFailures(ctx, limits[0].key); err != nil {
+		log.Error("error while resetting failed logins")
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newThrottledAuthService returns service with user 'steve' locked out after 3 failed logins
+func newThrottledAuthService(t *testing.T) *UserAuthService {
+	s, _ := newTestAuthService(t)
+	s.cfg.LoginFreeAttempts, s.cfg.LoginLockoutAttempts = 1, 3
+	s.cfg.LoginIPFreeAttempts, s.cfg.LoginIPLockoutAttempts = 1, 5
+	s.cfg.LoginBackoffBase, s.cfg.LoginBackoffMax = time.Millisecond, 2*time.Millisecond
+	s.cfg.LoginLockoutDuration, s.cfg.LoginFailureWindow = time.Minute, time.Minute
+	return s
+}
+
+func TestUserAuthService_GenerateToken_Throttling(t *testing.T) {
+	ctx := context.Background()
+	s := newThrottledAuthService(t)
+
+	_, _, _, err := s.GenerateToken(ctx, "nobody", "Stev13_jb7", "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "unknown user looks like incorrect password")
+	_, _, _, err = s.GenerateToken(ctx, "steve", "random", "10.0.0.2")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	// second failure is delayed by backoff
+	_, _, _, err = s.GenerateToken(ctx, "steve", "random", "10.0.0.2")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, _, _, err = s.GenerateToken(ctx, "steve", "Stev13_jb7", "10.0.0.2")
+	assert.ErrorIs(t, err, ErrTooManyAttempts, "correct password is rejected while logins are blocked")
+
+	time.Sleep(5 * time.Millisecond)
+	_, _, _, err = s.GenerateToken(ctx, "steve", "random", "10.0.0.3")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, _, _, err = s.GenerateToken(ctx, "steve", "Stev13_jb7", "10.0.0.4")
+	var throttled *ThrottledError
+	require.ErrorAs(t, err, &throttled, "user is locked out from any IP")
+	assert.Greater(t, throttled.RetryAfter, 50*time.Second)
+}
+
+func TestUserAuthService_GenerateToken_ThrottlingByIP(t *testing.T) {
+	ctx := context.Background()
+	s := newThrottledAuthService(t)
+	for _, username := range []string{"alice", "bob", "carol", "dave", "erin"} {
+		time.Sleep(5 * time.Millisecond)
+		_, _, _, err := s.GenerateToken(ctx, username, "random", "10.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	_, _, _, err := s.GenerateToken(ctx, "steve", "Stev13_jb7", "10.0.0.1")
+	assert.ErrorIs(t, err, ErrTooManyAttempts, "IP is locked out")
+	_, _, _, err = s.GenerateToken(ctx, "steve", "Stev13_jb7", "10.0.0.2")
+	assert.NoError(t, err, "other IPs aren't affected")
+}
+
+func TestUserAuthService_LoginBackoff(t *testing.T) {
+	s := &UserAuthService{cfg: testPasswordConfig()}
+	s.cfg.LoginBackoffBase, s.cfg.LoginBackoffMax, s.cfg.LoginLockoutDuration = time.Second, 5*time.Second, time.Hour
+	limit := loginLimit{free: 2, lockout: 10}
+
+	TestTable := []struct {
+		failures    int64
+		exceptDelay time.Duration
+	}{
+		{failures: 1, exceptDelay: 0},
+		{failures: 2, exceptDelay: 0},
+		{failures: 3, exceptDelay: time.Second},
+		{failures: 4, exceptDelay: 2 * time.Second},
+		{failures: 5, exceptDelay: 4 * time.Second},
+		{failures: 6, exceptDelay: 5 * time.Second},
+		{failures: 9, exceptDelay: 5 * time.Second},
+		{failures: 10, exceptDelay: time.Hour},
+	}
+	for _, TestCase := range TestTable {
+		assert.Equal(t, TestCase.exceptDelay, s.loginBackoff(limit, TestCase.failures), "failures: %d", TestCase.failures)
+	}
+}
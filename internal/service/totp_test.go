@@ -0,0 +1,71 @@
+package service
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is a secret of SHA-1 test vectors of RFC 6238
+var rfcSecret = base32NoPadding.EncodeToString([]byte("12345678901234567890"))
+
+func TestValidateTOTP(t *testing.T) {
+	// codes are last 6 digits of 8-digit values of RFC 6238, appendix B
+	TestTable := []struct {
+		name string
+		unix int64
+		code string
+	}{
+		{name: "59", unix: 59, code: "287082"},
+		{name: "1111111109", unix: 1111111109, code: "081804"},
+		{name: "1111111111", unix: 1111111111, code: "050471"},
+		{name: "1234567890", unix: 1234567890, code: "005924"},
+		{name: "2000000000", unix: 2000000000, code: "279037"},
+	}
+	for _, TestCase := range TestTable {
+		t.Run(TestCase.name, func(t *testing.T) {
+			now := time.Unix(TestCase.unix, 0)
+			step := TestCase.unix / totpPeriod
+			counter, ok := validateTOTP(rfcSecret, TestCase.code, now, 0)
+			assert.True(t, ok)
+			assert.Equal(t, step, counter)
+			counter, ok = validateTOTP(rfcSecret, TestCase.code, now.Add(totpPeriod*time.Second), 0)
+			assert.True(t, ok, "clock drift")
+			assert.Equal(t, step, counter)
+			_, ok = validateTOTP(rfcSecret, TestCase.code, now.Add(3*totpPeriod*time.Second), 0)
+			assert.False(t, ok, "expired")
+			_, ok = validateTOTP(rfcSecret, TestCase.code, now, step)
+			assert.False(t, ok, "used")
+		})
+	}
+	_, ok := validateTOTP(rfcSecret, "", time.Unix(59, 0), 0)
+	assert.False(t, ok)
+	_, ok = validateTOTP("not base32!", "287082", time.Unix(59, 0), 0)
+	assert.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri, err := url.Parse(totpURI("CatsGo", "steve", rfcSecret))
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/CatsGo:steve", uri.Path)
+	assert.Equal(t, rfcSecret, uri.Query().Get("secret"))
+	assert.Equal(t, "CatsGo", uri.Query().Get("issuer"))
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, hashes, err := newRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, recoveryCodesCount)
+	require.Len(t, hashes, recoveryCodesCount)
+	for i, code := range codes {
+		assert.Len(t, code, 19)
+		assert.Equal(t, hashes[i], hashRecoveryCode(strings.ToUpper(strings.ReplaceAll(code, "-", ""))),
+			"dashes and case don't matter")
+	}
+}
@@ -3,26 +3,26 @@ package main
 import (
 	"CatsGo/internal/configs"
 	"CatsGo/internal/handler"
+	"CatsGo/internal/mailer"
+	"CatsGo/internal/models"
 	repo "CatsGo/internal/repository"
 	"CatsGo/internal/request"
 	"CatsGo/internal/service"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/caarlos0/env/v6"
 
-	"github.com/go-redis/redis/v8"
-
-	"github.com/jackc/pgx/v4/pgxpool"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-
-	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	log "github.com/sirupsen/logrus"
@@ -32,58 +32,12 @@ import (
 )
 
 const (
-	flag     = "postgres" // postgres / mongodb
 	portEcho = ":8000"
 	dir      = "files/media/"
+	// shutdownTimeout limits how long requests in progress are awaited on shutdown
+	shutdownTimeout = 10 * time.Second
 )
 
-// NewPgxPool provides connection with postgres database
-func NewPgxPool(ctx context.Context, cfg *configs.Config) (*pgxpool.Pool, error) {
-	url := fmt.Sprintf("postgres://%s:%s@%s:%s/%s",
-		cfg.PgUser,
-		cfg.PgPassword,
-		cfg.PgHost,
-		cfg.PgPort,
-		cfg.PgDBName)
-	conn, cfgErr := pgxpool.Connect(ctx, url)
-	if cfgErr != nil {
-		log.Errorf("unable to connect to postgres database: %v\n", cfgErr)
-		return nil, fmt.Errorf("we can't connect to postgres database")
-	}
-	return conn, nil
-}
-
-// NewMongoClient provides connection with mongo database
-func NewMongoClient(ctx context.Context, cfg *configs.Config) (*mongo.Client, error) {
-	url := fmt.Sprintf("mongodb://%s:%s@%s:%s",
-		cfg.MongoUser,
-		cfg.MongoPassword,
-		cfg.MongoHost,
-		cfg.MongoPort)
-	client, err := mongo.NewClient(options.Client().ApplyURI(url))
-	if err != nil {
-		log.Error(err)
-		return nil, fmt.Errorf("we can't setup connection with mongo database")
-	}
-	err = client.Connect(ctx)
-	if err != nil {
-		log.Errorf("unable to connect to mongo database: %v\n", err)
-		return nil, fmt.Errorf("we can't connect to mongo database")
-	}
-	return client, nil
-}
-
-// NewRedisClient provides connection with redis
-func NewRedisClient(cfg *configs.Config) (*redis.Client, error) {
-	rHostPort := cfg.RedisHost + ":" + cfg.RedisPort
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     rHostPort,
-		Password: "", // no password set
-		DB:       0,  // use default DB
-	})
-	return rdb, nil
-}
-
 // @title Cats Go
 // @version 1.0
 // @description This is a simple CRUD app for Go.
@@ -97,7 +51,12 @@ func NewRedisClient(cfg *configs.Config) (*redis.Client, error) {
 
 func main() {
 	e := echo.New()
-	e.Validator = &request.CustomValidator{Validator: validator.New()}
+	validate, err := request.NewCustomValidator()
+	if err != nil {
+		log.Fatal(err)
+	}
+	e.Validator = validate
+	e.HTTPErrorHandler = handler.ErrorHandler
 
 	// Configuration
 	cfg := &configs.Config{}
@@ -105,6 +64,14 @@ func main() {
 	if err := env.Parse(cfg, *opts); err != nil {
 		log.Fatal(err)
 	}
+	flag.StringVar(&cfg.Backend, "backend", cfg.Backend,
+		"storage backend: "+strings.Join(repo.Backends(), " / "))
+	flag.Parse()
+	// IP of client limits failed logins, so it's taken from X-Forwarded-For only behind trusted proxy
+	e.IPExtractor = echo.ExtractIPDirect()
+	if cfg.TrustProxy {
+		e.IPExtractor = echo.ExtractIPFromXFFHeader()
+	}
 
 	// Middleware
 	e.Use(middleware.Logger())
@@ -114,64 +81,103 @@ func main() {
 		return c.String(http.StatusOK, "Hello, this is Cats Go app!")
 	})
 
-	var (
-		rps     repo.Repository
-		rpsAuth repo.Auth
-	)
-
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 	defer cancel()
 
-	if flag == "postgres" {
-		// postgres connect
-		conn, err := NewPgxPool(ctx, cfg)
-		if err != nil {
-			log.Panic(err)
-		}
-		defer conn.Close()
-
-		rps = repo.NewPostgresRepository(conn)
-		rpsAuth = repo.NewPostgresRepository(conn)
-	} else if flag == "mongodb" {
-		// mongodb connect
-		client, err := NewMongoClient(ctx, cfg)
-		if err != nil {
-			log.Panic(err)
-		}
-
-		rps = repo.NewMongoRepository(client, cfg)
-		rpsAuth = repo.NewMongoRepository(client, cfg)
+	backend, err := repo.Open(ctx, cfg.Backend, cfg)
+	if err != nil {
+		log.Panic(err)
+	}
+	defer backend.Close()
+	rps, rpsAuth := backend.Repository, backend.Auth
+
+	var rds *repo.RedisRepository
+	if cfg.CacheEnabled {
+		rdb := repo.NewRedisClient(cfg)
+		defer rdb.Close()
+		rds = repo.NewRedisRepository(rdb, cfg)
+		rps = repo.NewCachedRepository(rps, rds, cfg)
 	}
 
-	// redis connect
-	rdb, err := NewRedisClient(cfg)
+	mail, err := mailer.New(cfg)
+	if err != nil {
+		log.Panic(err)
+	}
+	keys, err := service.NewKeySet(cfg)
+	if err != nil {
+		log.Panic(err)
+	}
+	srvAuth, err := service.NewUserAuthService(rpsAuth, backend.Tokens, backend.Attempts, mail, keys, cfg)
 	if err != nil {
 		log.Panic(err)
 	}
-	rds := repo.NewRedisRepository(rdb)
-
-	var srv service.Service = service.NewCatService(rps, *rds)
-	hndlr := handler.NewCatHandler(srv)
-
-	e.GET("/cats", hndlr.GetAllCats)
-	e.POST("/cats", hndlr.CreateCat)
-	e.GET("/cats/:id", hndlr.GetCat)
-	e.PUT("/cats/:id", hndlr.UpdateCat)
-	e.DELETE("/cats/:id", hndlr.DeleteCat)
-
-	var srvAuth service.Auth = service.NewUserAuthService(rpsAuth, cfg)
 	hndlrAuth := handler.NewUserAuthHandler(srvAuth)
 	e.POST("/register", hndlrAuth.SignUp)
 	e.POST("/login", hndlrAuth.SignIn)
+	e.POST("/login/mfa", hndlrAuth.SignInMFA)
 	e.POST("/token", hndlrAuth.UpdateTokens)
+	e.POST("/password/forgot", hndlrAuth.ForgotPassword)
+	e.POST("/password/reset", hndlrAuth.ResetPassword)
+	e.POST("/email/verify", hndlrAuth.VerifyEmail)
+	e.GET("/.well-known/jwks.json", hndlrAuth.JWKS)
+
+	config := middleware.JWTConfig{
+		ParseTokenFunc: func(auth string, c echo.Context) (interface{}, error) {
+			return service.VerifyToken(auth, keys)
+		},
+	}
+	authenticated := []echo.MiddlewareFunc{
+		middleware.JWTWithConfig(config), handler.AccessTokenOnly, hndlrAuth.CheckToken,
+	}
+	// routes open to machine clients accept API keys with 'scope' as well as access tokens
+	withScope := func(scope string) []echo.MiddlewareFunc {
+		jwtConfig := config
+		jwtConfig.Skipper = handler.HasAPIKey
+		return []echo.MiddlewareFunc{
+			hndlrAuth.APIKeyAuth, middleware.JWTWithConfig(jwtConfig), handler.AccessTokenOnly,
+			hndlrAuth.CheckToken, handler.RequireScope(scope),
+		}
+	}
+	// with appends middleware to copy of 'chain', so chains shared by routes aren't changed
+	with := func(chain []echo.MiddlewareFunc, more ...echo.MiddlewareFunc) []echo.MiddlewareFunc {
+		return append(chain[:len(chain):len(chain)], more...)
+	}
+	// ids in paths are parsed before handlers, malformed ones are rejected with 400
+	idParam := handler.UUIDParam("id")
+	admin := with(authenticated, handler.RequireRole(models.RoleAdmin))
+	e.POST("/logout", hndlrAuth.Logout, authenticated...)
+	e.POST("/logout/all", hndlrAuth.LogoutAll, authenticated...)
+	e.PUT("/admin/users/:id/role", hndlrAuth.SetUserRole, with(admin, idParam)...)
+	// counters of cache are internal, only admins see them
+	if rds != nil {
+		e.GET("/cache/stats", func(c echo.Context) error {
+			return c.JSON(http.StatusOK, rds.Stats())
+		}, admin...)
+	}
+	e.POST("/me/email/verify", hndlrAuth.SendVerification, authenticated...)
+	e.POST("/me/mfa/totp", hndlrAuth.EnrollTOTP, authenticated...)
+	e.POST("/me/mfa/totp/confirm", hndlrAuth.ConfirmTOTP, authenticated...)
+	e.DELETE("/me/mfa/totp", hndlrAuth.DisableTOTP, authenticated...)
+	e.POST("/me/api-keys", hndlrAuth.CreateAPIKey, authenticated...)
+	e.GET("/me/api-keys", hndlrAuth.ListAPIKeys, authenticated...)
+	e.DELETE("/me/api-keys/:id", hndlrAuth.RevokeAPIKey, with(authenticated, idParam)...)
+
+	var srv service.Service = service.NewCatService(rps)
+	hndlr := handler.NewCatHandler(srv)
+	// only staff and admins change cats, service lets them change only their own cats unless they are admins
+	catsWrite := with(withScope(models.ScopeCatsWrite), handler.RequireRole(models.RoleStaff, models.RoleAdmin))
+
+	e.GET("/cats", hndlr.GetAllCats)
+	e.POST("/cats", hndlr.CreateCat, catsWrite...)
+	e.GET("/cats/search", hndlr.SearchCats)
+	e.GET("/cats/:id", hndlr.GetCat, idParam)
+	e.PUT("/cats/:id", hndlr.UpdateCat, with(catsWrite, idParam)...)
+	e.DELETE("/cats/:id", hndlr.DeleteCat, with(catsWrite, idParam)...)
+	e.GET("/me/cats", hndlr.MyCats, withScope(models.ScopeCatsRead)...)
 
 	r := e.Group("/restrict")
 	{
-		config := middleware.JWTConfig{
-			Claims:     new(service.JwtCustomClaims),
-			SigningKey: []byte(cfg.KeyForSignatureJwt),
-		}
-		r.Use(middleware.JWTWithConfig(config))
+		r.Use(authenticated...)
 		r.GET("", hndlrAuth.Restricted)
 	}
 
@@ -227,5 +233,21 @@ func main() {
 	})
 
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
-	e.Logger.Fatal(e.Start(portEcho))
+
+	go func() {
+		if err := e.Start(portEcho); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.Logger.Fatal(err)
+		}
+	}()
+
+	// on SIGINT or SIGTERM server stops taking requests, then emails sent in background are awaited
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer shutdownCancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		log.Error(err)
+	}
+	srvAuth.Close()
 }